@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	redfish "git.ypbind.de/repository/go-redfish.git"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Authentication methods
+const (
+	AUTH_SESSION string = "session"
+	AUTH_BASIC   string = "basic"
+)
+
+// BmcClient - Redfish client for the management board, using the data types of go-redfish.
+// In contrast to go-redfish the authentication (session or HTTP basic authentication) and the HTTP transport
+// can be configured.
+type BmcClient struct {
+	Hostname string
+	Port     int
+	Username string
+	Password string
+	Tls      TlsOptions
+	Timeout  time.Duration
+	// URL of the HTTP or SOCKS5 proxy, e.g. http://proxy:3128 or socks5://jump:1080
+	Proxy string
+	// log requests to standard error, see VERBOSE_*
+	Verbosity int
+	// store the responses of all GET requests in directory Record
+	Record string
+	// read the responses of GET requests from directory Replay instead of the management board, see Record
+	Replay string
+	// AUTH_SESSION (default) or AUTH_BASIC
+	Auth string
+	// number of retries of GET requests and login after transient errors, the delay between
+	// retries starts at RetryDelay and is doubled for every retry
+	Retries    uint
+	RetryDelay time.Duration
+	// no retries will be started if they can't be finished before Deadline (unless it is zero)
+	Deadline time.Time
+	// use fresh responses of GET requests stored by earlier or concurrent runs instead of requesting them
+	ResponseCache *ResponseCache
+	// called once before the first request after Initialise which isn't answered by ResponseCache,
+	// e.g. to log in only if the management board has to be queried
+	OnDemandLogin func() error
+
+	// session, set by Login
+	AuthToken       *string
+	SessionLocation *string
+
+	client      *http.Client
+	retried     int
+	initialised bool
+	logged_in   bool
+	login_err   error
+
+	// endpoints reported by the service root
+	chassis  string
+	systems  string
+	sessions string
+}
+
+// ProxyError - the proxy could not be reached or refused the connection to the management board
+type ProxyError struct {
+	Proxy string
+	Err   error
+}
+
+func (e ProxyError) Error() string {
+	return fmt.Sprintf("Proxy %s failed: %s", e.Proxy, e.Err.Error())
+}
+
+func (e ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// HttpStatusError - the management board answered with an unexpected HTTP status
+type HttpStatusError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Status     string
+}
+
+func (e HttpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %s for %s returned \"%s\" instead of \"200 OK\"", e.Method, e.Url, e.Status)
+}
+
+type odataLink struct {
+	Id string `json:"@odata.id"`
+}
+
+type serviceRoot struct {
+	Chassis        odataLink `json:"Chassis"`
+	Systems        odataLink `json:"Systems"`
+	SessionService odataLink `json:"SessionService"`
+	Links          struct {
+		Sessions odataLink `json:"Sessions"`
+	} `json:"Links"`
+}
+
+type collection struct {
+	Members []odataLink `json:"Members"`
+}
+
+func (c *BmcClient) baseUrl() string {
+	if c.Port > 0 {
+		return "https://" + c.Hostname + ":" + strconv.Itoa(c.Port)
+	}
+	return "https://" + c.Hostname
+}
+
+// retryableStatus reports if HTTP status code indicates a busy management board
+func retryableStatus(code int) bool {
+	return code == http.StatusServiceUnavailable || code == http.StatusBadGateway ||
+		code == http.StatusGatewayTimeout || code == http.StatusTooManyRequests
+}
+
+// RetryCount returns the number of retries of all requests
+func (c *BmcClient) RetryCount() int {
+	return c.retried
+}
+
+// httpRequestWithRetry sends the request like httpRequest but retries it after connection errors and if the
+// management board is busy. POST requests (login) are only retried if the request can't have been processed,
+// i.e. the connection was refused or the management board answered with a busy status, because a retry after
+// e.g. a timeout could create a second session.
+func (c *BmcClient) httpRequestWithRetry(method string, endpoint string, body []byte, expected_status int) ([]byte, http.Header, error) {
+	var attempt uint
+	delay := c.RetryDelay
+
+	for {
+		content, header, err := c.httpRequest(method, endpoint, body, expected_status)
+		if err == nil || attempt >= c.Retries {
+			return content, header, err
+		}
+
+		e, is_status := err.(HttpStatusError)
+		if is_status && !retryableStatus(e.StatusCode) {
+			return content, header, err
+		}
+
+		if !is_status && method == "POST" && !errors.Is(err, syscall.ECONNREFUSED) {
+			return content, header, err
+		}
+
+		// don't start a retry that can't be finished in time
+		if !c.Deadline.IsZero() && time.Now().Add(delay+c.Timeout).After(c.Deadline) {
+			return content, header, err
+		}
+
+		attempt++
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "Retry %d of %d for %s %s in %s: %s", attempt, c.Retries, method, endpoint, delay.String(), err.Error())
+
+		time.Sleep(delay)
+		delay *= 2
+		c.retried++
+	}
+}
+
+// httpRequest sends a request for endpoint to the management board and returns the body of the response
+func (c *BmcClient) httpRequest(method string, endpoint string, body []byte, expected_status int) ([]byte, http.Header, error) {
+	var reader io.Reader
+
+	if c.Replay != "" {
+		if method != "GET" {
+			return nil, nil, errors.New(fmt.Sprintf("BUG: HTTP %s can't be replayed", method))
+		}
+
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "Replaying GET %s from %s", endpoint, c.Replay)
+		content, err := ReplayResponse(c.Replay, endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		Trace(c.Verbosity, VERBOSE_BODIES, "Response body: %s", RedactBody(content))
+		return content, http.Header{}, nil
+	}
+
+	if c.client == nil {
+		return nil, nil, errors.New("BUG: Client has not been initialised")
+	}
+
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	request, err := http.NewRequest(method, c.baseUrl()+endpoint, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.Auth == AUTH_BASIC {
+		request.SetBasicAuth(c.Username, c.Password)
+	} else if c.AuthToken != nil {
+		request.Header.Set("X-Auth-Token", *c.AuthToken)
+	}
+
+	// the login request and response contain credentials
+	login := method == "POST" && endpoint == c.sessions
+
+	Trace(c.Verbosity, VERBOSE_REQUESTS, "%s %s", method, request.URL.String())
+	Trace(c.Verbosity, VERBOSE_HEADERS, "Request headers:\n%s", RedactHeaders(request.Header))
+	if body != nil {
+		if login {
+			Trace(c.Verbosity, VERBOSE_BODIES, "Request body: %s", REDACTED)
+		} else {
+			Trace(c.Verbosity, VERBOSE_BODIES, "Request body: %s", RedactBody(body))
+		}
+	}
+
+	// the tunnel through the proxy has been established once the TLS handshake with the management board starts
+	var tunnel int32
+	if c.Proxy != "" {
+		request = request.WithContext(httptrace.WithClientTrace(request.Context(), &httptrace.ClientTrace{
+			TLSHandshakeStart: func() { atomic.StoreInt32(&tunnel, 1) },
+			GotConn:           func(httptrace.GotConnInfo) { atomic.StoreInt32(&tunnel, 1) },
+		}))
+	}
+
+	start := time.Now()
+	response, err := c.client.Do(request)
+	if err != nil {
+		err = c.classifyError(err, atomic.LoadInt32(&tunnel) == 1)
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "%s %s failed after %s: %s", method, request.URL.String(), time.Since(start).String(), err.Error())
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	Trace(c.Verbosity, VERBOSE_REQUESTS, "%s %s returned \"%s\" after %s", method, request.URL.String(), response.Status, time.Since(start).String())
+	Trace(c.Verbosity, VERBOSE_HEADERS, "Response headers:\n%s", RedactHeaders(response.Header))
+	if login {
+		Trace(c.Verbosity, VERBOSE_BODIES, "Response body: %s", REDACTED)
+	} else {
+		Trace(c.Verbosity, VERBOSE_BODIES, "Response body: %s", RedactBody(content))
+	}
+
+	if response.StatusCode != expected_status {
+		return nil, nil, HttpStatusError{
+			Method:     method,
+			Url:        c.baseUrl() + endpoint,
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+		}
+	}
+
+	if c.Record != "" && method == "GET" {
+		err = RecordResponse(c.Record, endpoint, content)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("Can't record response of %s: %s", endpoint, err.Error()))
+		}
+	}
+
+	return content, response.Header, nil
+}
+
+// classifyError converts errors caused by the proxy to ProxyError. Requests failing before the tunnel through the
+// proxy has been established (e.g. a proxy accepting connections but never answering) failed because of the proxy.
+func (c *BmcClient) classifyError(err error, tunnel bool) error {
+	var op_err *net.OpError
+	var proxy_err ProxyError
+	var url_err *url.Error
+
+	if c.Proxy == "" {
+		return err
+	}
+
+	if errors.As(err, &proxy_err) {
+		return proxy_err
+	}
+
+	if errors.As(err, &op_err) && (op_err.Op == "proxyconnect" || strings.HasPrefix(op_err.Op, "socks")) {
+		return ProxyError{Proxy: redactProxy(c.Proxy), Err: op_err.Err}
+	}
+
+	if !tunnel {
+		if errors.As(err, &url_err) {
+			err = url_err.Err
+		}
+		return ProxyError{Proxy: redactProxy(c.Proxy), Err: err}
+	}
+
+	return err
+}
+
+// redactProxy removes the password from the proxy URL
+func redactProxy(proxy string) string {
+	parsed, err := url.Parse(proxy)
+	if err != nil {
+		return proxy
+	}
+	return parsed.Redacted()
+}
+
+// get fetches endpoint from the management board, calling OnDemandLogin first if required
+func (c *BmcClient) get(endpoint string) ([]byte, error) {
+	if c.initialised && c.OnDemandLogin != nil && !c.logged_in {
+		c.logged_in = true
+		c.login_err = c.OnDemandLogin()
+	}
+	if c.login_err != nil {
+		return nil, c.login_err
+	}
+
+	content, _, err := c.httpRequestWithRetry("GET", endpoint, nil, http.StatusOK)
+	return content, err
+}
+
+// getJson fetches endpoint (from the response cache if configured) and decodes the result into v
+func (c *BmcClient) getJson(endpoint string, v interface{}) error {
+	var content []byte
+	var err error
+
+	// replayed responses are read from files anyway
+	if c.ResponseCache != nil && c.Replay == "" {
+		content, err = c.cachedGet(endpoint)
+	} else {
+		content, err = c.get(endpoint)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(content, v)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Can't decode data of %s: %s", endpoint, err.Error()))
+	}
+	return nil
+}
+
+// newHttpClient creates the HTTP client using the TLS and proxy settings
+func (c *BmcClient) newHttpClient() (*http.Client, error) {
+	tls_config, err := NewTlsConfig(c.Tls)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: tls_config,
+	}
+
+	if c.Proxy != "" {
+		proxy_url, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid proxy URL: %s", err.Error()))
+		}
+
+		transport.Proxy = http.ProxyURL(proxy_url)
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "Using proxy %s", redactProxy(c.Proxy))
+		// the HTTP client doesn't report a refused CONNECT as proxy error
+		transport.OnProxyConnectResponse = func(_ context.Context, _ *url.URL, _ *http.Request, response *http.Response) error {
+			if response.StatusCode != http.StatusOK {
+				return ProxyError{Proxy: redactProxy(c.Proxy), Err: errors.New(response.Status)}
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout:   c.Timeout,
+		Transport: transport,
+	}, nil
+}
+
+// Initialise sets up the HTTP client and reads the endpoints from the service root
+func (c *BmcClient) Initialise() error {
+	var root serviceRoot
+
+	if c.Auth == "" {
+		c.Auth = AUTH_SESSION
+	}
+
+	// replayed responses are read from files, the HTTP client is never used
+	if c.Replay == "" {
+		client, err := c.newHttpClient()
+		if err != nil {
+			return err
+		}
+		c.client = client
+	}
+
+	err := c.getJson("/redfish/v1/", &root)
+	if err != nil {
+		return err
+	}
+
+	c.chassis = root.Chassis.Id
+	c.systems = root.Systems.Id
+	c.sessions = root.Links.Sessions.Id
+
+	// some implementations don't report the sessions endpoint in the service root
+	if c.sessions == "" && root.SessionService.Id != "" {
+		var session_service struct {
+			Sessions odataLink `json:"Sessions"`
+		}
+
+		err = c.getJson(root.SessionService.Id, &session_service)
+		if err == nil {
+			c.sessions = session_service.Sessions.Id
+		}
+	}
+
+	c.initialised = true
+	return nil
+}
+
+// Login creates a session, for HTTP basic authentication and replayed responses nothing has to be done
+func (c *BmcClient) Login() error {
+	if c.Auth == AUTH_BASIC || c.Replay != "" {
+		return nil
+	}
+
+	if c.sessions == "" {
+		return errors.New("No Sessions endpoint reported by the service root")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"UserName": c.Username,
+		"Password": c.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, header, err := c.httpRequestWithRetry("POST", c.sessions, body, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+
+	token := header.Get("X-Auth-Token")
+	if token == "" {
+		return errors.New("BUG: HTTP POST to SessionService endpoint did not return a X-Auth-Token header")
+	}
+
+	// Location can be an absolute URL or a path
+	location := header.Get("Location")
+	parsed, err := url.Parse(location)
+	if err == nil && parsed.Path != "" {
+		location = parsed.Path
+	}
+
+	c.AuthToken = &token
+	c.SessionLocation = &location
+	return nil
+}
+
+// ValidateSession checks the session by an inexpensive request, bypassing the response cache
+func (c *BmcClient) ValidateSession() error {
+	if c.systems == "" {
+		return errors.New("BUG: Endpoint of the systems is not known, has the client been initialised?")
+	}
+
+	_, _, err := c.httpRequestWithRetry("GET", c.systems, nil, http.StatusOK)
+	return err
+}
+
+// Logout removes the session
+func (c *BmcClient) Logout() error {
+	if c.Auth == AUTH_BASIC || c.AuthToken == nil {
+		return nil
+	}
+
+	if c.SessionLocation == nil || *c.SessionLocation == "" {
+		return errors.New("BUG: No session location found")
+	}
+
+	_, _, err := c.httpRequest("DELETE", *c.SessionLocation, nil, http.StatusOK)
+	if err != nil {
+		// some implementations answer with 204 No Content
+		if e, ok := err.(HttpStatusError); !ok || e.StatusCode != http.StatusNoContent {
+			return err
+		}
+	}
+
+	c.AuthToken = nil
+	c.SessionLocation = nil
+	return nil
+}
+
+// Close closes the idle connections kept alive by the HTTP client, e.g. after the exporter collected the metrics
+func (c *BmcClient) Close() {
+	if c.client != nil {
+		c.client.CloseIdleConnections()
+	}
+}
+
+func (c *BmcClient) getMembers(endpoint string) ([]string, error) {
+	var result = make([]string, 0)
+	var coll collection
+
+	if endpoint == "" {
+		return result, errors.New("BUG: Endpoint of the collection is not known, has the client been initialised?")
+	}
+
+	err := c.getJson(endpoint, &coll)
+	if err != nil {
+		return result, err
+	}
+
+	for _, m := range coll.Members {
+		result = append(result, m.Id)
+	}
+	return result, nil
+}
+
+// GetChassis returns the endpoints of all chassis
+func (c *BmcClient) GetChassis() ([]string, error) {
+	return c.getMembers(c.chassis)
+}
+
+func (c *BmcClient) GetChassisData(endpoint string) (*redfish.ChassisData, error) {
+	var result redfish.ChassisData
+
+	err := c.getJson(endpoint, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MapChassisById returns the data of all chassis, indexed by the Id
+func (c *BmcClient) MapChassisById() (map[string]*redfish.ChassisData, error) {
+	return MapChassisById(c)
+}
+
+func (c *BmcClient) GetThermalData(endpoint string) (*redfish.ThermalData, error) {
+	var result redfish.ThermalData
+
+	err := c.getJson(endpoint, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *BmcClient) GetPowerData(endpoint string) (*redfish.PowerData, error) {
+	var result redfish.PowerData
+
+	err := c.getJson(endpoint, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetSystems returns the endpoints of all systems
+func (c *BmcClient) GetSystems() ([]string, error) {
+	return c.getMembers(c.systems)
+}
+
+func (c *BmcClient) GetSystemData(endpoint string) (*redfish.SystemData, error) {
+	var result redfish.SystemData
+
+	err := c.getJson(endpoint, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MapSystemsById returns the data of all systems, indexed by the Id
+func (c *BmcClient) MapSystemsById() (map[string]*redfish.SystemData, error) {
+	return MapSystemsById(c)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newRetryClient returns a client for the management board host:port, retrying requests twice
+func newRetryClient(t *testing.T, host string, port int) *BmcClient {
+	rf := &BmcClient{
+		Hostname:   host,
+		Port:       port,
+		Username:   MOCK_USER,
+		Password:   MOCK_PASSWORD,
+		Tls:        TlsOptions{InsecureSSL: true},
+		Timeout:    200 * time.Millisecond,
+		Retries:    2,
+		RetryDelay: 10 * time.Millisecond,
+		sessions:   "/redfish/v1/SessionService/Sessions",
+	}
+
+	client, err := rf.newHttpClient()
+	if err != nil {
+		t.Fatalf("Can't create HTTP client: %s", err.Error())
+	}
+	rf.client = client
+	return rf
+}
+
+func TestLoginNoRetryAfterTimeout(t *testing.T) {
+	var mutex sync.Mutex
+	var logins int
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		logins++
+		mutex.Unlock()
+
+		// the session may have been created, but the response is too late
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	rf := newRetryClient(t, addr.IP.String(), addr.Port)
+
+	err := rf.Login()
+	if err == nil {
+		t.Fatalf("Expected a timeout")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if logins != 1 || rf.RetryCount() != 0 {
+		t.Errorf("Login has been retried after a timeout (%d requests)", logins)
+	}
+}
+
+func TestLoginRetryBusy(t *testing.T) {
+	var mutex sync.Mutex
+	var logins int
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		logins++
+		if logins == 1 {
+			http.Error(w, "Busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Auth-Token", "token")
+		w.Header().Set("Location", "/redfish/v1/SessionService/Sessions/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	rf := newRetryClient(t, addr.IP.String(), addr.Port)
+
+	err := rf.Login()
+	if err != nil {
+		t.Fatalf("Login failed: %s", err.Error())
+	}
+	if rf.RetryCount() != 1 {
+		t.Errorf("Expected 1 retry but got %d", rf.RetryCount())
+	}
+}
+
+func TestLoginRetryRefused(t *testing.T) {
+	// a closed listener provides a port refusing connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	rf := newRetryClient(t, "127.0.0.1", port)
+
+	err = rf.Login()
+	if err == nil {
+		t.Fatalf("Expected a connection error")
+	}
+	if rf.RetryCount() != 2 {
+		t.Errorf("Expected 2 retries of the refused login but got %d: %s", rf.RetryCount(), err.Error())
+	}
+}
+
+func TestProxyError(t *testing.T) {
+	m := NewMockBmc(t, "dell_idrac", nil)
+
+	// a closed listener provides a port refusing connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := listener.Addr().String()
+	listener.Close()
+
+	// accepts connections but never answers
+	hanging, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hanging.Close()
+	go func() {
+		var conns = make([]net.Conn, 0)
+		for {
+			conn, err := hanging.Accept()
+			if err != nil {
+				for _, c := range conns {
+					c.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer rejecting.Close()
+
+	for _, proxy := range []string{
+		"http://user:secret@" + refused,
+		"socks5://" + refused,
+		rejecting.URL,
+		"http://" + hanging.Addr().String(),
+		"socks5://" + hanging.Addr().String(),
+	} {
+		expected := "Proxy " + redactProxy(proxy) + " failed: "
+
+		for name, request := range map[string]func(rf *BmcClient) error{
+			"Initialise": func(rf *BmcClient) error {
+				return rf.Initialise()
+			},
+			"Login": func(rf *BmcClient) error {
+				return rf.Login()
+			},
+			"ValidateSession": func(rf *BmcClient) error {
+				return rf.ValidateSession()
+			},
+			"MapChassisById": func(rf *BmcClient) error {
+				_, err := rf.MapChassisById()
+				return err
+			},
+		} {
+			// initialise without proxy, so all requests can be tested
+			rf := m.NewClient()
+			err = rf.Initialise()
+			if err != nil {
+				t.Fatalf("Initialisation failed: %s", err.Error())
+			}
+
+			rf.Proxy = proxy
+			rf.Timeout = 200 * time.Millisecond
+			rf.client, err = rf.newHttpClient()
+			if err != nil {
+				t.Fatalf("Can't create HTTP client: %s", err.Error())
+			}
+
+			err = request(rf)
+			if _, ok := err.(ProxyError); !ok || !strings.HasPrefix(err.Error(), expected) {
+				t.Errorf("%s using %s: expected a proxy error but got %v", name, proxy, err)
+			}
+		}
+	}
+
+	// errors of the management board reached through the proxy aren't proxy errors
+	tunnel := httptest.NewServer(http.HandlerFunc(connectProxy))
+	defer tunnel.Close()
+
+	rf := m.NewClient()
+	rf.Proxy = tunnel.URL
+	rf.Password = "wrong"
+	err = rf.Initialise()
+	if err != nil {
+		t.Fatalf("Initialisation through the proxy failed: %s", err.Error())
+	}
+	err = rf.Login()
+	if e, ok := err.(HttpStatusError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected HTTP status 401 but got %v", err)
+	}
+}
+
+// connectProxy is a HTTP proxy supporting CONNECT only
+func connectProxy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	w.WriteHeader(http.StatusOK)
+	conn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	go io.Copy(upstream, buffered)
+	io.Copy(conn, upstream)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	redfish "git.ypbind.de/repository/go-redfish.git"
+)
+
+// CachedClient - RedfishClient reading every resource only once from Client and returning the cached data for
+// further requests of the same URI, e.g. the Thermal resource used by the thermal and the fans check.
+// Errors aren't cached. The data is cached for the lifetime of the CachedClient, which should be a single run.
+type CachedClient struct {
+	Client RedfishClient
+
+	chassis []string
+	systems []string
+	// indexed by URI
+	chassis_data map[string]*redfish.ChassisData
+	thermal_data map[string]*redfish.ThermalData
+	power_data   map[string]*redfish.PowerData
+	system_data  map[string]*redfish.SystemData
+}
+
+func NewCachedClient(client RedfishClient) *CachedClient {
+	return &CachedClient{
+		Client:       client,
+		chassis_data: make(map[string]*redfish.ChassisData),
+		thermal_data: make(map[string]*redfish.ThermalData),
+		power_data:   make(map[string]*redfish.PowerData),
+		system_data:  make(map[string]*redfish.SystemData),
+	}
+}
+
+func (c *CachedClient) GetChassis() ([]string, error) {
+	if c.chassis != nil {
+		return c.chassis, nil
+	}
+
+	result, err := c.Client.GetChassis()
+	if err != nil {
+		return result, err
+	}
+
+	c.chassis = result
+	return result, nil
+}
+
+func (c *CachedClient) GetChassisData(endpoint string) (*redfish.ChassisData, error) {
+	cached, found := c.chassis_data[endpoint]
+	if found {
+		return cached, nil
+	}
+
+	result, err := c.Client.GetChassisData(endpoint)
+	if err != nil {
+		return result, err
+	}
+
+	c.chassis_data[endpoint] = result
+	return result, nil
+}
+
+func (c *CachedClient) MapChassisById() (map[string]*redfish.ChassisData, error) {
+	return MapChassisById(c)
+}
+
+func (c *CachedClient) GetThermalData(endpoint string) (*redfish.ThermalData, error) {
+	cached, found := c.thermal_data[endpoint]
+	if found {
+		return cached, nil
+	}
+
+	result, err := c.Client.GetThermalData(endpoint)
+	if err != nil {
+		return result, err
+	}
+
+	c.thermal_data[endpoint] = result
+	return result, nil
+}
+
+func (c *CachedClient) GetPowerData(endpoint string) (*redfish.PowerData, error) {
+	cached, found := c.power_data[endpoint]
+	if found {
+		return cached, nil
+	}
+
+	result, err := c.Client.GetPowerData(endpoint)
+	if err != nil {
+		return result, err
+	}
+
+	c.power_data[endpoint] = result
+	return result, nil
+}
+
+func (c *CachedClient) GetSystems() ([]string, error) {
+	if c.systems != nil {
+		return c.systems, nil
+	}
+
+	result, err := c.Client.GetSystems()
+	if err != nil {
+		return result, err
+	}
+
+	c.systems = result
+	return result, nil
+}
+
+func (c *CachedClient) GetSystemData(endpoint string) (*redfish.SystemData, error) {
+	cached, found := c.system_data[endpoint]
+	if found {
+		return cached, nil
+	}
+
+	result, err := c.Client.GetSystemData(endpoint)
+	if err != nil {
+		return result, err
+	}
+
+	c.system_data[endpoint] = result
+	return result, nil
+}
+
+func (c *CachedClient) MapSystemsById() (map[string]*redfish.SystemData, error) {
+	return MapSystemsById(c)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCachedClient(t *testing.T) {
+	for _, profile := range []string{"hpe_ilo", "dell_idrac", "lenovo_xcc", "supermicro"} {
+		t.Run(profile, func(t *testing.T) {
+			m := NewMockBmc(t, profile, nil)
+			rf := NewCachedClient(m.Login(t))
+
+			CheckThermal(rf, ALL_MEMBERS, nil, nil, nil)
+			CheckFans(rf, ALL_MEMBERS, nil, nil, nil)
+			CheckVoltages(rf, ALL_MEMBERS, nil, nil, nil)
+			CheckPsu(rf, ALL_MEMBERS, 1, 1)
+			CheckGeneralHealth(rf, ALL_MEMBERS)
+			CheckInstalledCpus(rf, "1", 1)
+			CheckInstalledMemory(rf, "1", 1)
+
+			var requested = make(map[string]int)
+			for _, r := range m.Requests() {
+				requested[r]++
+				if requested[r] > 1 {
+					t.Errorf("%s has been requested more than once", r)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedClientErrors(t *testing.T) {
+	m := NewMockBmc(t, "supermicro", map[string]string{"/redfish/v1/Chassis/1/Thermal": ""})
+	rf := NewCachedClient(m.Login(t))
+
+	for i := 0; i < 2; i++ {
+		_, err := CheckThermal(rf, "", nil, nil, nil)
+		if err == nil {
+			t.Fatalf("Expected an error for the missing Thermal endpoint")
+		}
+	}
+
+	// errors aren't cached
+	var count int
+	for _, r := range m.Requests() {
+		if r == "GET /redfish/v1/Chassis/1/Thermal" {
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 requests of the missing Thermal endpoint but got %d", count)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+// CheckOptions - options shared by all checks
+type CheckOptions struct {
+	ChassisId string
+	SystemId  string
+	Rules     *ThresholdRules
+}
+
+// Check - a single check, selected on the command line by -check-<name>
+type Check interface {
+	// Name of the check, the command line option is -check-<name>
+	Name() string
+	// Usage returns the argument placeholder (empty if the option takes no argument) and the help text of the option
+	Usage() (string, string)
+	// RegisterFlags adds the command line option(s) of the check
+	RegisterFlags()
+	// Requested reports if the check has been requested on the command line
+	Requested() bool
+	// Validate checks the option values of a requested check before connecting to the management board
+	Validate() error
+	// Run the check
+	Run(rf RedfishClient, opts CheckOptions) (NagiosState, error)
+}
+
+// CheckOption - additional command line option of a check, shown in the usage text
+type CheckOption struct {
+	Option string
+	Help   string
+}
+
+// CheckWithOptions - check that has additional command line options
+type CheckWithOptions interface {
+	Options() []CheckOption
+}
+
+var registered_checks = make([]Check, 0)
+var default_check Check
+
+// RegisterCheck adds a check to the list of available checks
+func RegisterCheck(c Check) {
+	registered_checks = append(registered_checks, c)
+}
+
+// RegisterDefaultCheck adds a check that will be run if no check has been requested
+func RegisterDefaultCheck(c Check) {
+	RegisterCheck(c)
+	default_check = c
+}
+
+// RegisteredChecks returns all available checks in order of registration
+func RegisteredChecks() []Check {
+	return registered_checks
+}
+
+// RegisterCheckFlags adds command line options for all available checks
+func RegisterCheckFlags() {
+	for _, c := range registered_checks {
+		c.RegisterFlags()
+	}
+}
+
+// RequestedChecks returns all checks requested on the command line or the default check if none was requested
+func RequestedChecks() []Check {
+	var result = make([]Check, 0)
+
+	for _, c := range registered_checks {
+		if c.Requested() {
+			result = append(result, c)
+		}
+	}
+
+	if len(result) == 0 && default_check != nil {
+		result = append(result, default_check)
+	}
+	return result
+}
+
+// RunChecks runs all checks using the same session and merges the results
+func RunChecks(rf RedfishClient, checks []Check, opts CheckOptions) NagiosState {
+	var result = NagiosState{
+		Critical: make([]string, 0),
+		Warning:  make([]string, 0),
+		Ok:       make([]string, 0),
+		Unknown:  make([]string, 0),
+		PerfData: make([]string, 0),
+	}
+
+	for _, c := range checks {
+		SetPhase("check " + c.Name())
+		state, err := c.Run(rf, opts)
+
+		// don't lose errors that have not been reported as unknown state by the check
+		if err != nil && len(state.Unknown) == 0 {
+			state.Unknown = append(state.Unknown, err.Error())
+		}
+
+		result = MergeNagiosState(result, state)
+	}
+
+	return result
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	redfish "git.ypbind.de/repository/go-redfish.git"
+	"strings"
+)
+
+// ALL_MEMBERS - value for -chassis-id/-system-id to check every chassis/system
+const ALL_MEMBERS string = "all"
+
+// MissingEndpointError - the chassis or system doesn't provide the endpoint a check needs
+type MissingEndpointError struct {
+	Message string
+}
+
+func (e MissingEndpointError) Error() string {
+	return e.Message
+}
+
+// ChassisCheckFunc - check a single chassis
+type ChassisCheckFunc func(RedfishClient, *redfish.ChassisData) (NagiosState, error)
+
+// SystemCheckFunc - check a single system
+type SystemCheckFunc func(RedfishClient, *redfish.SystemData) (NagiosState, error)
+
+func ChassisId(c *redfish.ChassisData) string {
+	if c.Id == nil {
+		return ""
+	}
+	return *c.Id
+}
+
+func SystemId(s *redfish.SystemData) string {
+	if s.Id == nil {
+		return ""
+	}
+	return *s.Id
+}
+
+// GetChassisMembers returns the first chassis if cha_id is empty, every chassis if cha_id is "all" or the chassis with ID cha_id
+func GetChassisMembers(rf RedfishClient, cha_id string) ([]*redfish.ChassisData, error) {
+	var result = make([]*redfish.ChassisData, 0)
+
+	if cha_id == "" || cha_id == ALL_MEMBERS {
+		cha_epl, err := rf.GetChassis()
+		if err != nil {
+			return result, err
+		}
+
+		// should never happen
+		if len(cha_epl) == 0 {
+			return result, errors.New("BUG: No chassis endpoint reported at all")
+		}
+
+		// no chassis specified, pick the first reported chassis
+		if cha_id == "" {
+			cha_epl = cha_epl[0:1]
+		}
+
+		for _, ep := range cha_epl {
+			chassis_data, err := rf.GetChassisData(ep)
+			if err != nil {
+				return result, err
+			}
+			result = append(result, chassis_data)
+		}
+		return result, nil
+	}
+
+	cha_data_map, err := rf.MapChassisById()
+	if err != nil {
+		return result, err
+	}
+
+	chassis_data, found := cha_data_map[cha_id]
+	if !found {
+		return result, errors.New(fmt.Sprintf("Chassis with ID %s not found", cha_id))
+	}
+
+	return append(result, chassis_data), nil
+}
+
+// GetSystemMembers returns the first system if sys_id is empty, every system if sys_id is "all" or the system with ID sys_id
+func GetSystemMembers(rf RedfishClient, sys_id string) ([]*redfish.SystemData, error) {
+	var result = make([]*redfish.SystemData, 0)
+
+	if sys_id == "" || sys_id == ALL_MEMBERS {
+		sys_epl, err := rf.GetSystems()
+		if err != nil {
+			return result, err
+		}
+
+		// should never happen
+		if len(sys_epl) == 0 {
+			return result, errors.New("BUG: No system endpoint reported at all")
+		}
+
+		// no system specified, pick the first reported system
+		if sys_id == "" {
+			sys_epl = sys_epl[0:1]
+		}
+
+		for _, ep := range sys_epl {
+			system_data, err := rf.GetSystemData(ep)
+			if err != nil {
+				return result, err
+			}
+			result = append(result, system_data)
+		}
+		return result, nil
+	}
+
+	sys_data_map, err := rf.MapSystemsById()
+	if err != nil {
+		return result, err
+	}
+
+	system_data, found := sys_data_map[sys_id]
+	if !found {
+		return result, errors.New(fmt.Sprintf("System with ID %s not found", sys_id))
+	}
+
+	return append(result, system_data), nil
+}
+
+// CheckEachChassis runs check for the chassis selected by cha_id, see GetChassisMembers
+func CheckEachChassis(rf RedfishClient, cha_id string, check ChassisCheckFunc) (NagiosState, error) {
+	var states = make([]NagiosState, 0)
+	var errs = make([]error, 0)
+	var ids = make([]string, 0)
+
+	members, err := GetChassisMembers(rf, cha_id)
+	if err != nil {
+		return unknownState(err), err
+	}
+
+	for _, chassis_data := range members {
+		state, err := check(rf, chassis_data)
+		states = append(states, state)
+		errs = append(errs, err)
+		ids = append(ids, ChassisId(chassis_data))
+	}
+
+	if cha_id != ALL_MEMBERS {
+		return states[0], errs[0]
+	}
+	return mergeMemberStates("Chassis", ids, states, errs)
+}
+
+// CheckEachSystem runs check for the systems selected by sys_id, see GetSystemMembers
+func CheckEachSystem(rf RedfishClient, sys_id string, check SystemCheckFunc) (NagiosState, error) {
+	var states = make([]NagiosState, 0)
+	var errs = make([]error, 0)
+	var ids = make([]string, 0)
+
+	members, err := GetSystemMembers(rf, sys_id)
+	if err != nil {
+		return unknownState(err), err
+	}
+
+	for _, system_data := range members {
+		state, err := check(rf, system_data)
+		states = append(states, state)
+		errs = append(errs, err)
+		ids = append(ids, SystemId(system_data))
+	}
+
+	if sys_id != ALL_MEMBERS {
+		return states[0], errs[0]
+	}
+	return mergeMemberStates("System", ids, states, errs)
+}
+
+func unknownState(err error) NagiosState {
+	return NagiosState{
+		Critical: make([]string, 0),
+		Warning:  make([]string, 0),
+		Ok:       make([]string, 0),
+		Unknown:  []string{err.Error()},
+		PerfData: make([]string, 0),
+	}
+}
+
+// mergeMemberStates prefixes messages and performance data labels with the member ID and merges the results.
+// Members that don't provide the endpoint required by the check are skipped.
+func mergeMemberStates(kind string, ids []string, states []NagiosState, errs []error) (NagiosState, error) {
+	var result = NagiosState{
+		Critical: make([]string, 0),
+		Warning:  make([]string, 0),
+		Ok:       make([]string, 0),
+		Unknown:  make([]string, 0),
+		PerfData: make([]string, 0),
+	}
+	var checked int
+	var last_err error
+
+	for i, state := range states {
+		if _, missing := errs[i].(MissingEndpointError); missing {
+			last_err = errs[i]
+			continue
+		}
+
+		if errs[i] != nil {
+			last_err = errs[i]
+			if len(state.Unknown) == 0 {
+				state.Unknown = append(state.Unknown, errs[i].Error())
+			}
+		}
+
+		checked++
+		result = MergeNagiosState(result, prefixNagiosState(state, fmt.Sprintf("%s %s: ", kind, ids[i]), ids[i]))
+	}
+
+	if checked == 0 {
+		result.Unknown = append(result.Unknown, fmt.Sprintf("No %s provides the data required for this check: %s", strings.ToLower(kind), last_err.Error()))
+		return result, last_err
+	}
+
+	return result, nil
+}
+
+func prefixNagiosState(n NagiosState, msg_prefix string, member string) NagiosState {
+	var result = NagiosState{
+		Critical: make([]string, 0),
+		Warning:  make([]string, 0),
+		Ok:       make([]string, 0),
+		Unknown:  make([]string, 0),
+		PerfData: make([]string, 0),
+	}
+
+	for _, m := range n.Critical {
+		result.Critical = append(result.Critical, msg_prefix+m)
+	}
+	for _, m := range n.Warning {
+		result.Warning = append(result.Warning, msg_prefix+m)
+	}
+	for _, m := range n.Ok {
+		result.Ok = append(result.Ok, msg_prefix+m)
+	}
+	for _, m := range n.Unknown {
+		result.Unknown = append(result.Unknown, msg_prefix+m)
+	}
+	for _, p := range n.PerfData {
+		result.PerfData = append(result.PerfData, PrefixPerfDataLabel(p, member+"_"))
+	}
+	for _, c := range n.Components {
+		c.Member = member
+		c.Message = msg_prefix + c.Message
+		result.Components = append(result.Components, c)
+	}
+
+	return result
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
 	"strconv"
 	"strings"
 )
 
-func CheckFans(rf redfish.Redfish, cha_id string) (NagiosState, error) {
-	var chassis_data *redfish.ChassisData
-	var found bool
+func CheckFans(rf RedfishClient, cha_id string, warn *Range, crit *Range, rules *ThresholdRules) (NagiosState, error) {
+	return CheckEachChassis(rf, cha_id, func(rf RedfishClient, chassis_data *redfish.ChassisData) (NagiosState, error) {
+		return checkFansChassis(rf, chassis_data, warn, crit, rules)
+	})
+}
+
+func checkFansChassis(rf RedfishClient, chassis_data *redfish.ChassisData, warn *Range, crit *Range, rules *ThresholdRules) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
@@ -18,43 +23,12 @@ func CheckFans(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 		Unknown:  make([]string, 0),
 		PerfData: make([]string, 0),
 	}
-
-	// no system specified, pick the first reported system
-	if cha_id == "" {
-		cha_epl, err := rf.GetChassis()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(cha_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No chassis  endpoint reported at all")
-			return state, errors.New("BUG: No chassis endpoint reported at all")
-		}
-
-		chassis_data, err = rf.GetChassisData(cha_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		cha_data_map, err := rf.MapChassisById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		chassis_data, found = cha_data_map[cha_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("Chassis with ID %s not found", cha_id))
-			return state, errors.New(fmt.Sprintf("Chassis with ID %s not found", cha_id))
-		}
-	}
+	var cha_id = ChassisId(chassis_data)
 
 	// check for "Thermal" endpoint
 	if chassis_data.Thermal == nil {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("No Thermal endpoint defined for chassis with ID %s", cha_id))
-		return state, errors.New(fmt.Sprintf("No Thermal endpoint defined for chassis with ID %s", cha_id))
+		return state, MissingEndpointError{Message: fmt.Sprintf("No Thermal endpoint defined for chassis with ID %s", cha_id)}
 	}
 
 	if chassis_data.Thermal.Id == nil || *chassis_data.Thermal.Id == "" {
@@ -77,6 +51,13 @@ func CheckFans(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 			tmp_name = *tmp.FanName
 		}
 
+		tmp_warn, tmp_crit := rules.Thresholds("fans", tmp_name, warn, crit)
+
+		// thresholds reported by the management board are used unless the user supplied thresholds
+		tmp_warn, tmp_crit = BoardThresholds(tmp_warn, tmp_crit,
+			IntThreshold(tmp.LowerThresholdNonCritical), IntThreshold(tmp.LowerThresholdCritical),
+			IntThreshold(tmp.UpperThresholdNonCritical), IntThreshold(tmp.UpperThresholdCritical))
+
 		if tmp.Status.State == nil || *tmp.Status.State == "" {
 			continue
 		}
@@ -108,12 +89,35 @@ func CheckFans(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 			           Fans.Status seem to only to use Enabled/Disabled as state
 		*/
 		if tmp_state == "enabled" {
+			component := ComponentState{
+				Name:     tmp_name,
+				Type:     "fan",
+				Health:   *tmp.Status.Health,
+				Unit:     "RPM",
+				Warning:  tmp_warn.String(),
+				Critical: tmp_crit.String(),
+			}
+
+			// evaluate reading against thresholds
+			reading_state := NAGIOS_OK
+			reading_str := ""
+			if tmp.Reading != nil {
+				reading := float64(*tmp.Reading)
+				component.Reading = &reading
+				reading_state = ThresholdState(reading, tmp_warn, tmp_crit)
+				reading_str = strconv.FormatFloat(reading, 'f', -1, 64)
+			}
+
 			if tmp_health == "critical" {
-				state.Critical = append(state.Critical, fmt.Sprintf("Fan \"%s\" is reported as critical", tmp_name))
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Fan \"%s\" is reported as critical", tmp_name), component)
+			} else if reading_state == NAGIOS_CRITICAL {
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Fan \"%s\" reports %s RPM, critical range is %s", tmp_name, reading_str, tmp_crit.String()), component)
 			} else if tmp_health == "warning" {
-				state.Warning = append(state.Warning, fmt.Sprintf("Fan \"%s\" is reported as warning", tmp_name))
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("Fan \"%s\" is reported as warning", tmp_name), component)
+			} else if reading_state == NAGIOS_WARNING {
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("Fan \"%s\" reports %s RPM, warning range is %s", tmp_name, reading_str, tmp_warn.String()), component)
 			} else if tmp_health == "ok" {
-				state.Ok = append(state.Ok, fmt.Sprintf("Fan \"%s\" is reported as ok", tmp_name))
+				ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("Fan \"%s\" is reported as ok", tmp_name), component)
 			}
 
 			if tmp.Reading != nil && *tmp.Reading > 0 {
@@ -131,13 +135,12 @@ func CheckFans(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 					_max = strconv.FormatInt(int64(*tmp.MaxReadingRange), 10)
 				}
 
-				// we report _upper_ ranges but _lower_ ranges also exist
-				if tmp.UpperThresholdNonCritical != nil && *tmp.UpperThresholdNonCritical > 0 {
-					_wrn = strconv.FormatInt(int64(*tmp.UpperThresholdNonCritical), 10)
+				if tmp_warn != nil {
+					_wrn = tmp_warn.String()
 				}
 
-				if tmp.UpperThresholdCritical != nil && *tmp.UpperThresholdCritical > 0 {
-					_crt = strconv.FormatInt(int64(*tmp.UpperThresholdCritical), 10)
+				if tmp_crit != nil {
+					_crt = tmp_crit.String()
 				}
 
 				label := fmt.Sprintf("RPM_%s", tmp_name)
@@ -152,3 +155,42 @@ func CheckFans(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 
 	return state, nil
 }
+
+// FansCheck - check system fans
+type FansCheck struct {
+	ThresholdFlags
+	enabled bool
+}
+
+func init() {
+	RegisterCheck(&FansCheck{})
+}
+
+func (c *FansCheck) Name() string {
+	return "fans"
+}
+
+func (c *FansCheck) Usage() (string, string) {
+	return "", "Check system fans"
+}
+
+func (c *FansCheck) RegisterFlags() {
+	c.RegisterThresholdFlags(c.Name())
+	flag.BoolVar(&c.enabled, "check-"+c.Name(), false, "Check system fans")
+}
+
+func (c *FansCheck) Requested() bool {
+	return c.enabled
+}
+
+func (c *FansCheck) Options() []CheckOption {
+	return c.ThresholdOptions(c.Name(), "RPM")
+}
+
+func (c *FansCheck) Validate() error {
+	return c.ParseThresholdFlags(c.Name())
+}
+
+func (c *FansCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckFans(rf, opts.ChassisId, c.Warning, c.Critical, opts.Rules)
+}
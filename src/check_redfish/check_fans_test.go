@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckFans(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO reports FanName instead of Name",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_OK,
+			messages: []string{"Fan \"Fan 1\" is reported as ok", "Fan \"Fan 2\" is reported as ok"},
+			perfdata: 0,
+		},
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"Fan \"System Board Fan1A\" is reported as ok", "Fan \"System Board Fan2A\" reports 600 RPM, warning range is 840:"},
+			perfdata: 2,
+		},
+		{
+			name:     "Lenovo XCC reports upper case state",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_OK,
+			messages: []string{"Fan \"Fan 1 Tach\" is reported as ok", "Fan \"Fan 2 Tach\" is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Supermicro",
+			profile:  "supermicro",
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Fan \"FAN1\" is reported as ok", "Fan \"FAN2\" is reported as critical"},
+			perfdata: 1,
+		},
+		{
+			name:     "Chassis without thermal data",
+			profile:  "supermicro",
+			patch:    map[string]string{"/redfish/v1/Chassis/1": `{"Id": "1", "Status": {"Health": "OK", "State": "Enabled"}}`},
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"No Thermal endpoint defined for chassis with ID 1"},
+			perfdata: 0,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckFans(rf, member, nil, nil, nil)
+	})
+}
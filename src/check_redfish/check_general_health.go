@@ -2,52 +2,26 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
 	"strings"
 )
 
-func CheckGeneralHealth(rf redfish.Redfish, sys_id string) (NagiosState, error) {
-	var system_data *redfish.SystemData
-	var found bool
+func CheckGeneralHealth(rf RedfishClient, sys_id string) (NagiosState, error) {
+	return CheckEachSystem(rf, sys_id, func(rf RedfishClient, system_data *redfish.SystemData) (NagiosState, error) {
+		return checkGeneralHealthSystem(rf, system_data)
+	})
+}
+
+func checkGeneralHealthSystem(rf RedfishClient, system_data *redfish.SystemData) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
 		Ok:       make([]string, 0),
 		Unknown:  make([]string, 0),
 	}
-
-	// no system specified, pick the first reported system
-	if sys_id == "" {
-		sys_epl, err := rf.GetSystems()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(sys_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No system endpoint reported at all")
-			return state, errors.New("BUG: No system endpoint reported at all")
-		}
-
-		system_data, err = rf.GetSystemData(sys_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		sys_data_map, err := rf.MapSystemsById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		system_data, found = sys_data_map[sys_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("System with ID %s not found", sys_id))
-			return state, errors.New(fmt.Sprintf("System with ID %s not found", sys_id))
-		}
-	}
+	var sys_id = SystemId(system_data)
 
 	if system_data.Status.State == nil || *system_data.Status.State == "" {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("System with ID %s do not define a state", sys_id))
@@ -63,20 +37,29 @@ func CheckGeneralHealth(rf redfish.Redfish, sys_id string) (NagiosState, error)
 			return state, errors.New(fmt.Sprintf("System with ID %s do not define a health state", sys_id))
 		}
 
+		component := ComponentState{
+			Name:   sys_id,
+			Type:   "system",
+			Health: *system_data.Status.Health,
+		}
+		if system_data.SerialNumber != nil {
+			component.SerialNumber = *system_data.SerialNumber
+		}
+
 		l_health := strings.ToLower(*system_data.Status.Health)
 		if l_health == "ok" {
-			state.Ok = append(state.Ok, "General health is reported as OK")
+			ReportComponent(&state, NAGIOS_OK, "General health is reported as OK", component)
 		} else if l_health == "warning" {
-			state.Warning = append(state.Warning, "General health is reported as warning")
+			ReportComponent(&state, NAGIOS_WARNING, "General health is reported as warning", component)
 		} else if l_health == "critical" {
-			state.Critical = append(state.Critical, "General health is reported as critical")
+			ReportComponent(&state, NAGIOS_CRITICAL, "General health is reported as critical", component)
 		} else if l_health == "failed" {
 			// XXX: Although https://redfish.dmtf.org/schemas/v1/Resource.json#/definitions/Status only defines Ok, Warning and Critical some boards report
 			//      Failed as well
-			state.Critical = append(state.Critical, "General health is reported as failed")
+			ReportComponent(&state, NAGIOS_CRITICAL, "General health is reported as failed", component)
 		} else {
 			// XXX: there may be more non-standard health strings
-			state.Unknown = append(state.Unknown, fmt.Sprintf("General health is reported as \"%s\"", *system_data.Status.Health))
+			ReportComponent(&state, NAGIOS_UNKNOWN, fmt.Sprintf("General health is reported as \"%s\"", *system_data.Status.Health), component)
 		}
 	} else {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("System reports \"%s\" instead of \"Enabled\" for health information", *system_data.Status.State))
@@ -85,3 +68,36 @@ func CheckGeneralHealth(rf redfish.Redfish, sys_id string) (NagiosState, error)
 
 	return state, nil
 }
+
+// GeneralHealthCheck - check general health of the system
+type GeneralHealthCheck struct {
+	enabled bool
+}
+
+func init() {
+	RegisterDefaultCheck(&GeneralHealthCheck{})
+}
+
+func (c *GeneralHealthCheck) Name() string {
+	return "general-health"
+}
+
+func (c *GeneralHealthCheck) Usage() (string, string) {
+	return "", "Check general health. This is the default when no check has been requested"
+}
+
+func (c *GeneralHealthCheck) RegisterFlags() {
+	flag.BoolVar(&c.enabled, "check-"+c.Name(), false, "Check general health")
+}
+
+func (c *GeneralHealthCheck) Requested() bool {
+	return c.enabled
+}
+
+func (c *GeneralHealthCheck) Validate() error {
+	return nil
+}
+
+func (c *GeneralHealthCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckGeneralHealth(rf, opts.SystemId)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckGeneralHealth(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_OK,
+			messages: []string{"General health is reported as OK"},
+			perfdata: -1,
+		},
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			member:   "System.Embedded.1",
+			rc:       NAGIOS_OK,
+			messages: []string{"General health is reported as OK"},
+			perfdata: -1,
+		},
+		{
+			name:     "Lenovo XCC",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"General health is reported as warning"},
+			perfdata: -1,
+		},
+		{
+			name:     "Supermicro reports Failed health",
+			profile:  "supermicro",
+			member:   ALL_MEMBERS,
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"System 1: General health is reported as failed"},
+			perfdata: -1,
+		},
+		{
+			name:     "System not enabled",
+			profile:  "supermicro",
+			patch:    map[string]string{"/redfish/v1/Systems/1": `{"Id": "1", "Status": {"Health": "OK", "State": "StandbyOffline"}}`},
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"System reports \"StandbyOffline\" instead of \"Enabled\" for health information"},
+			perfdata: -1,
+			err:      true,
+		},
+		{
+			name:     "Unknown system",
+			profile:  "dell_idrac",
+			member:   "System.Embedded.2",
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"System with ID System.Embedded.2 not found"},
+			perfdata: -1,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckGeneralHealth(rf, member)
+	})
+}
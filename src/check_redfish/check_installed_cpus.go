@@ -2,51 +2,26 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
+	"strconv"
 )
 
-func CheckInstalledCpus(rf redfish.Redfish, sys_id string, c int) (NagiosState, error) {
-	var system_data *redfish.SystemData
-	var found bool
+func CheckInstalledCpus(rf RedfishClient, sys_id string, c int) (NagiosState, error) {
+	return CheckEachSystem(rf, sys_id, func(rf RedfishClient, system_data *redfish.SystemData) (NagiosState, error) {
+		return checkInstalledCpusSystem(rf, system_data, c)
+	})
+}
+
+func checkInstalledCpusSystem(rf RedfishClient, system_data *redfish.SystemData, c int) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
 		Ok:       make([]string, 0),
 		Unknown:  make([]string, 0),
 	}
-
-	// no system specified, pick the first reported system
-	if sys_id == "" {
-		sys_epl, err := rf.GetSystems()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(sys_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No system endpoint reported at all")
-			return state, errors.New("BUG: No system endpoint reported at all")
-		}
-
-		system_data, err = rf.GetSystemData(sys_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		sys_data_map, err := rf.MapSystemsById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		system_data, found = sys_data_map[sys_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("System with ID %s not found", sys_id))
-			return state, errors.New(fmt.Sprintf("System with ID %s not found", sys_id))
-		}
-	}
+	var sys_id = SystemId(system_data)
 
 	if system_data.ProcessorSummary == nil {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("No ProcessorSummary data found for system with ID %s", sys_id))
@@ -58,16 +33,62 @@ func CheckInstalledCpus(rf redfish.Redfish, sys_id string, c int) (NagiosState,
 		return state, errors.New(fmt.Sprintf("No Count reported in ProcessorSummary data for system with ID %s", sys_id))
 	}
 
+	reading := float64(system_data.ProcessorSummary.Count)
+	component := ComponentState{
+		Name:    sys_id,
+		Type:    "processors",
+		Reading: &reading,
+	}
+
 	if system_data.ProcessorSummary.Count < c {
-		state.Critical = append(state.Critical, fmt.Sprintf("Only %d CPUs (instead of %d) installed", system_data.ProcessorSummary.Count, c))
+		ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Only %d CPUs (instead of %d) installed", system_data.ProcessorSummary.Count, c), component)
 		return state, nil
 	}
 
 	if system_data.ProcessorSummary.Count > c {
-		state.Warning = append(state.Warning, fmt.Sprintf("%d CPUs (instead of %d) installed", system_data.ProcessorSummary.Count, c))
+		ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("%d CPUs (instead of %d) installed", system_data.ProcessorSummary.Count, c), component)
 		return state, nil
 	}
 
-	state.Ok = append(state.Ok, fmt.Sprintf("%d CPUs installed", system_data.ProcessorSummary.Count))
+	ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("%d CPUs installed", system_data.ProcessorSummary.Count), component)
 	return state, nil
 }
+
+// InstalledCpusCheck - check number of installed CPUs
+type InstalledCpusCheck struct {
+	cpus_str string
+	cpus     int
+}
+
+func init() {
+	RegisterCheck(&InstalledCpusCheck{})
+}
+
+func (c *InstalledCpusCheck) Name() string {
+	return "installed-cpus"
+}
+
+func (c *InstalledCpusCheck) Usage() (string, string) {
+	return "<cpu>", "Check number of installed cpus"
+}
+
+func (c *InstalledCpusCheck) RegisterFlags() {
+	flag.StringVar(&c.cpus_str, "check-"+c.Name(), "", "Check installed CPUs")
+}
+
+func (c *InstalledCpusCheck) Requested() bool {
+	return c.cpus_str != ""
+}
+
+func (c *InstalledCpusCheck) Validate() error {
+	n, err := strconv.Atoi(c.cpus_str)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Can't convert %s to a number: %s", c.cpus_str, err.Error()))
+	}
+	c.cpus = n
+	return nil
+}
+
+func (c *InstalledCpusCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckInstalledCpus(rf, opts.SystemId, c.cpus)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckInstalledCpus(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"2 CPUs (instead of 1) installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "Lenovo XCC",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_OK,
+			messages: []string{"1 CPUs installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "Supermicro",
+			profile:  "supermicro",
+			rc:       NAGIOS_OK,
+			messages: []string{"1 CPUs installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "No ProcessorSummary",
+			profile:  "lenovo_xcc",
+			patch:    map[string]string{"/redfish/v1/Systems/1": `{"Id": "1", "Status": {"Health": "OK", "State": "Enabled"}}`},
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"No ProcessorSummary data found for system with ID 1"},
+			perfdata: -1,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckInstalledCpus(rf, member, 1)
+	})
+
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_OK,
+			messages: []string{"2 CPUs installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "Lenovo XCC, missing CPU",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Only 1 CPUs (instead of 2) installed"},
+			perfdata: -1,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckInstalledCpus(rf, member, 2)
+	})
+}
@@ -2,51 +2,26 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
+	"strconv"
 )
 
-func CheckInstalledMemory(rf redfish.Redfish, sys_id string, m int) (NagiosState, error) {
-	var system_data *redfish.SystemData
-	var found bool
+func CheckInstalledMemory(rf RedfishClient, sys_id string, m int) (NagiosState, error) {
+	return CheckEachSystem(rf, sys_id, func(rf RedfishClient, system_data *redfish.SystemData) (NagiosState, error) {
+		return checkInstalledMemorySystem(rf, system_data, m)
+	})
+}
+
+func checkInstalledMemorySystem(rf RedfishClient, system_data *redfish.SystemData, m int) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
 		Ok:       make([]string, 0),
 		Unknown:  make([]string, 0),
 	}
-
-	// no system specified, pick the first reported system
-	if sys_id == "" {
-		sys_epl, err := rf.GetSystems()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(sys_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No system endpoint reported at all")
-			return state, errors.New("BUG: No system endpoint reported at all")
-		}
-
-		system_data, err = rf.GetSystemData(sys_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		sys_data_map, err := rf.MapSystemsById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		system_data, found = sys_data_map[sys_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("System with ID %s not found", sys_id))
-			return state, errors.New(fmt.Sprintf("System with ID %s not found", sys_id))
-		}
-	}
+	var sys_id = SystemId(system_data)
 
 	if system_data.MemorySummary == nil {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("No MemorySummary data found for system with ID %s", sys_id))
@@ -58,16 +33,63 @@ func CheckInstalledMemory(rf redfish.Redfish, sys_id string, m int) (NagiosState
 		return state, errors.New(fmt.Sprintf("No TotalSystemMemoryGiB reported in MemorySummary data for system with ID %s", sys_id))
 	}
 
+	reading := float64(system_data.MemorySummary.TotalSystemMemoryGiB)
+	component := ComponentState{
+		Name:    sys_id,
+		Type:    "memory",
+		Reading: &reading,
+		Unit:    "GiB",
+	}
+
 	if system_data.MemorySummary.TotalSystemMemoryGiB < m {
-		state.Critical = append(state.Critical, fmt.Sprintf("Only %d GiB of memory (instead of %d) installed", system_data.MemorySummary.TotalSystemMemoryGiB, m))
+		ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Only %d GiB of memory (instead of %d) installed", system_data.MemorySummary.TotalSystemMemoryGiB, m), component)
 		return state, nil
 	}
 
 	if system_data.MemorySummary.TotalSystemMemoryGiB > m {
-		state.Warning = append(state.Warning, fmt.Sprintf("%d GiB of memory (instead of %d) installed", system_data.MemorySummary.TotalSystemMemoryGiB, m))
+		ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("%d GiB of memory (instead of %d) installed", system_data.MemorySummary.TotalSystemMemoryGiB, m), component)
 		return state, nil
 	}
 
-	state.Ok = append(state.Ok, fmt.Sprintf("%d GiB of memory installed", system_data.MemorySummary.TotalSystemMemoryGiB))
+	ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("%d GiB of memory installed", system_data.MemorySummary.TotalSystemMemoryGiB), component)
 	return state, nil
 }
+
+// InstalledMemoryCheck - check amount of installed memory
+type InstalledMemoryCheck struct {
+	memory_str string
+	memory     int
+}
+
+func init() {
+	RegisterCheck(&InstalledMemoryCheck{})
+}
+
+func (c *InstalledMemoryCheck) Name() string {
+	return "installed-memory"
+}
+
+func (c *InstalledMemoryCheck) Usage() (string, string) {
+	return "<mem_gb>", "Check if installed memory is recognized with <mem_gb> GByte of memory"
+}
+
+func (c *InstalledMemoryCheck) RegisterFlags() {
+	flag.StringVar(&c.memory_str, "check-"+c.Name(), "", "Check installed memory")
+}
+
+func (c *InstalledMemoryCheck) Requested() bool {
+	return c.memory_str != ""
+}
+
+func (c *InstalledMemoryCheck) Validate() error {
+	m, err := strconv.Atoi(c.memory_str)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Can't convert %s to a number: %s", c.memory_str, err.Error()))
+	}
+	c.memory = m
+	return nil
+}
+
+func (c *InstalledMemoryCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckInstalledMemory(rf, opts.SystemId, c.memory)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckInstalledMemory(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"256 GiB of memory (instead of 192) installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			rc:       NAGIOS_OK,
+			messages: []string{"192 GiB of memory installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "Lenovo XCC",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Only 64 GiB of memory (instead of 192) installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "Supermicro",
+			profile:  "supermicro",
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Only 32 GiB of memory (instead of 192) installed"},
+			perfdata: -1,
+		},
+		{
+			name:     "No MemorySummary",
+			profile:  "hpe_ilo",
+			patch:    map[string]string{"/redfish/v1/Systems/1/": `{"Id": "1", "Status": {"Health": "OK", "State": "Enabled"}}`},
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"No MemorySummary data found for system with ID 1"},
+			perfdata: -1,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckInstalledMemory(rf, member, 192)
+	})
+}
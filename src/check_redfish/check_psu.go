@@ -2,15 +2,20 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
 	"strconv"
 	"strings"
 )
 
-func CheckPsu(rf redfish.Redfish, cha_id string, warn int, crit int) (NagiosState, error) {
-	var chassis_data *redfish.ChassisData
-	var found bool
+func CheckPsu(rf RedfishClient, cha_id string, warn int, crit int) (NagiosState, error) {
+	return CheckEachChassis(rf, cha_id, func(rf RedfishClient, chassis_data *redfish.ChassisData) (NagiosState, error) {
+		return checkPsuChassis(rf, chassis_data, warn, crit)
+	})
+}
+
+func checkPsuChassis(rf RedfishClient, chassis_data *redfish.ChassisData, warn int, crit int) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
@@ -20,43 +25,12 @@ func CheckPsu(rf redfish.Redfish, cha_id string, warn int, crit int) (NagiosStat
 	}
 	var psu_count int
 	var working_psu_count int
-
-	// no system specified, pick the first reported system
-	if cha_id == "" {
-		cha_epl, err := rf.GetChassis()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(cha_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No chassis  endpoint reported at all")
-			return state, errors.New("BUG: No chassis endpoint reported at all")
-		}
-
-		chassis_data, err = rf.GetChassisData(cha_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		cha_data_map, err := rf.MapChassisById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		chassis_data, found = cha_data_map[cha_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("Chassis with ID %s not found", cha_id))
-			return state, errors.New(fmt.Sprintf("Chassis with ID %s not found", cha_id))
-		}
-	}
+	var cha_id = ChassisId(chassis_data)
 
 	// check for "Power" endpoint
 	if chassis_data.Power == nil {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("No Power endpoint defined for chassis with ID %s", cha_id))
-		return state, errors.New(fmt.Sprintf("No Power endpoint defined for chassis with ID %s", cha_id))
+		return state, MissingEndpointError{Message: fmt.Sprintf("No Power endpoint defined for chassis with ID %s", cha_id)}
 	}
 
 	if chassis_data.Power.Id == nil || *chassis_data.Power.Id == "" {
@@ -92,6 +66,20 @@ func CheckPsu(rf redfish.Redfish, cha_id string, warn int, crit int) (NagiosStat
 		psu_health := strings.ToLower(*psu.Status.Health)
 
 		psu_state := strings.ToLower(*psu.Status.State)
+
+		component := ComponentState{
+			Name:   psu_name,
+			Type:   "psu",
+			Health: *psu.Status.Health,
+			Unit:   "W",
+		}
+		if psu.SerialNumber != nil {
+			component.SerialNumber = *psu.SerialNumber
+		}
+		if psu.LastPowerOutputWatts != nil {
+			reading := float64(*psu.LastPowerOutputWatts)
+			component.Reading = &reading
+		}
 		/*
 			According to the specification at https://redfish.dmtf.org/schemas/v1/Resource.json#/definitions/State possible
 			values are:
@@ -112,38 +100,38 @@ func CheckPsu(rf redfish.Redfish, cha_id string, warn int, crit int) (NagiosStat
 		if psu_state == "enabled" || psu_state == "standbyspare" || psu_state == "quiesced" {
 			psu_count += 1
 			if psu_health == "critical" {
-				state.Critical = append(state.Critical, fmt.Sprintf("PSU %s (SN: %s) is reported as critical", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("PSU %s (SN: %s) is reported as critical", psu_name, psu_sn), component)
 			} else if psu_health == "warning" {
-				state.Warning = append(state.Warning, fmt.Sprintf("PSU %s (SN: %s) is reported as warning", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("PSU %s (SN: %s) is reported as warning", psu_name, psu_sn), component)
 			} else if psu_health == "ok" {
-				state.Ok = append(state.Ok, fmt.Sprintf("PSU %s (SN: %s) is reported as ok", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("PSU %s (SN: %s) is reported as ok", psu_name, psu_sn), component)
 				working_psu_count += 1
 			}
 		} else if psu_state == "standbyoffline" || psu_state == "intest" || psu_state == "disabled" || psu_state == "starting" || psu_state == "deferring" || psu_state == "updating" {
 			psu_count += 1
 			if psu_health == "critical" {
-				state.Critical = append(state.Critical, fmt.Sprintf("PSU %s (SN: %s) is reported as critical", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("PSU %s (SN: %s) is reported as critical", psu_name, psu_sn), component)
 			} else if psu_health == "warning" {
-				state.Warning = append(state.Warning, fmt.Sprintf("PSU %s (SN: %s) is reported as warning", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("PSU %s (SN: %s) is reported as warning", psu_name, psu_sn), component)
 			} else if psu_health == "ok" {
-				state.Ok = append(state.Ok, fmt.Sprintf("PSU %s (SN: %s) is reported as ok", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("PSU %s (SN: %s) is reported as ok", psu_name, psu_sn), component)
 				working_psu_count += 1
 			}
 		} else if psu_state == "unavailableoffline" {
 			psu_count += 1
 			if psu_health == "critical" {
-				state.Critical = append(state.Critical, fmt.Sprintf("PSU %s (SN: %s) is reported as critical", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("PSU %s (SN: %s) is reported as critical", psu_name, psu_sn), component)
 			} else if psu_health == "warning" {
-				state.Warning = append(state.Warning, fmt.Sprintf("PSU %s (SN: %s) is reported as warning", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("PSU %s (SN: %s) is reported as warning", psu_name, psu_sn), component)
 			} else if psu_health == "ok" {
-				state.Ok = append(state.Ok, fmt.Sprintf("PSU %s (SN: %s) is reported as ok", psu_name, psu_sn))
+				ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("PSU %s (SN: %s) is reported as ok", psu_name, psu_sn), component)
 				working_psu_count += 1
 			}
 		} else if psu_state == "absent" {
 			// nothing to do here
 			continue
 		} else {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("PSU %s (SN: %s) reports unknown state %s", psu_name, psu_sn, *psu.Status.State))
+			ReportComponent(&state, NAGIOS_UNKNOWN, fmt.Sprintf("PSU %s (SN: %s) reports unknown state %s", psu_name, psu_sn, *psu.Status.State), component)
 			continue
 		}
 
@@ -206,3 +194,63 @@ func CheckPsu(rf redfish.Redfish, cha_id string, warn int, crit int) (NagiosStat
 
 	return state, nil
 }
+
+// PsuCheck - check number of working power supplies
+type PsuCheck struct {
+	psu_str string
+	warn    int
+	crit    int
+}
+
+func init() {
+	RegisterCheck(&PsuCheck{})
+}
+
+func (c *PsuCheck) Name() string {
+	return "psu"
+}
+
+func (c *PsuCheck) Usage() (string, string) {
+	return "<warn>,<crit>", "Check installed PSU, report <warn>/<crit> if <warn>/<crit> or less working PSUs are reported"
+}
+
+func (c *PsuCheck) RegisterFlags() {
+	flag.StringVar(&c.psu_str, "check-"+c.Name(), "", "Check PSU")
+}
+
+func (c *PsuCheck) Requested() bool {
+	return c.psu_str != ""
+}
+
+func (c *PsuCheck) Validate() error {
+	splitted := strings.Split(c.psu_str, ",")
+	if len(splitted) != 2 {
+		return errors.New("Invalid format for -check-psu")
+	}
+
+	w, err := strconv.Atoi(splitted[0])
+	if err != nil {
+		return errors.New(fmt.Sprintf("Can't convert %s to a number: %s", splitted[0], err.Error()))
+	}
+
+	cr, err := strconv.Atoi(splitted[1])
+	if err != nil {
+		return errors.New(fmt.Sprintf("Can't convert %s to a number: %s", splitted[1], err.Error()))
+	}
+
+	if w <= 0 || cr <= 0 {
+		return errors.New("Warning and critical threshold must be greater than null")
+	}
+
+	if cr > w {
+		return errors.New("Critical threshold must be greater or equal than warning threshold")
+	}
+
+	c.warn = w
+	c.crit = cr
+	return nil
+}
+
+func (c *PsuCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckPsu(rf, opts.ChassisId, c.warn, c.crit)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckPsu(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_OK,
+			messages: []string{"2 out of 2 power supplies are working", "PSU HpServerPowerSupply 1 (SN: 5DMVV0A4D7E1AB) is reported as ok"},
+			perfdata: 4,
+		},
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			rc:       NAGIOS_OK,
+			messages: []string{"2 out of 2 power supplies are working"},
+			perfdata: 4,
+		},
+		{
+			name:     "Dell iDRAC, all chassis skips enclosure without power data",
+			profile:  "dell_idrac",
+			member:   ALL_MEMBERS,
+			rc:       NAGIOS_OK,
+			messages: []string{"Chassis System.Embedded.1: 2 out of 2 power supplies are working"},
+			perfdata: 4,
+		},
+		{
+			name:     "Lenovo XCC ignores absent PSU",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Only 1 out of 1 power supplies are working", "PSU PSU1 (SN: D1DG94C0A1B) is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Supermicro reports failed PSU",
+			profile:  "supermicro",
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Only 1 out of 2 power supplies are working"},
+			perfdata: 2,
+		},
+		{
+			name:     "No power supplies reported",
+			profile:  "supermicro",
+			patch:    map[string]string{"/redfish/v1/Chassis/1/Power": `{"PowerSupplies": []}`},
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"No working power supplies reported. This may be a firmware bug!"},
+			perfdata: 0,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckPsu(rf, member, 2, 2)
+	})
+}
@@ -2,15 +2,20 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
 	"strconv"
 	"strings"
 )
 
-func CheckThermal(rf redfish.Redfish, cha_id string) (NagiosState, error) {
-	var chassis_data *redfish.ChassisData
-	var found bool
+func CheckThermal(rf RedfishClient, cha_id string, warn *Range, crit *Range, rules *ThresholdRules) (NagiosState, error) {
+	return CheckEachChassis(rf, cha_id, func(rf RedfishClient, chassis_data *redfish.ChassisData) (NagiosState, error) {
+		return checkThermalChassis(rf, chassis_data, warn, crit, rules)
+	})
+}
+
+func checkThermalChassis(rf RedfishClient, chassis_data *redfish.ChassisData, warn *Range, crit *Range, rules *ThresholdRules) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
@@ -18,43 +23,12 @@ func CheckThermal(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 		Unknown:  make([]string, 0),
 		PerfData: make([]string, 0),
 	}
-
-	// no system specified, pick the first reported system
-	if cha_id == "" {
-		cha_epl, err := rf.GetChassis()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(cha_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No chassis  endpoint reported at all")
-			return state, errors.New("BUG: No chassis endpoint reported at all")
-		}
-
-		chassis_data, err = rf.GetChassisData(cha_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		cha_data_map, err := rf.MapChassisById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		chassis_data, found = cha_data_map[cha_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("Chassis with ID %s not found", cha_id))
-			return state, errors.New(fmt.Sprintf("Chassis with ID %s not found", cha_id))
-		}
-	}
+	var cha_id = ChassisId(chassis_data)
 
 	// check for "Thermal" endpoint
 	if chassis_data.Thermal == nil {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("No Thermal endpoint defined for chassis with ID %s", cha_id))
-		return state, errors.New(fmt.Sprintf("No Thermal endpoint defined for chassis with ID %s", cha_id))
+		return state, MissingEndpointError{Message: fmt.Sprintf("No Thermal endpoint defined for chassis with ID %s", cha_id)}
 	}
 
 	if chassis_data.Thermal.Id == nil || *chassis_data.Thermal.Id == "" {
@@ -74,6 +48,13 @@ func CheckThermal(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 			tmp_name = *tmp.Name
 		}
 
+		tmp_warn, tmp_crit := rules.Thresholds("thermal", tmp_name, warn, crit)
+
+		// thresholds reported by the management board are used unless the user supplied thresholds
+		tmp_warn, tmp_crit = BoardThresholds(tmp_warn, tmp_crit,
+			IntThreshold(tmp.LowerThresholdNonCritical), IntThreshold(tmp.LowerThresholdCritical),
+			IntThreshold(tmp.UpperThresholdNonCritical), IntThreshold(tmp.UpperThresholdCritical))
+
 		if tmp.Status.State == nil || *tmp.Status.State == "" {
 			continue
 		}
@@ -105,13 +86,37 @@ func CheckThermal(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 			           Temperatures.Status seem to only to use Enabled/Disabled as state
 		*/
 		if tmp_state == "enabled" {
+			component := ComponentState{
+				Name:     tmp_name,
+				Type:     "temperature",
+				Health:   *tmp.Status.Health,
+				Unit:     "Cel",
+				Warning:  tmp_warn.String(),
+				Critical: tmp_crit.String(),
+			}
+
+			// evaluate reading against thresholds
+			reading_state := NAGIOS_OK
+			reading_str := ""
+			if tmp.ReadingCelsius != nil {
+				reading := float64(*tmp.ReadingCelsius)
+				component.Reading = &reading
+				reading_state = ThresholdState(reading, tmp_warn, tmp_crit)
+				reading_str = strconv.FormatFloat(reading, 'f', -1, 64)
+			}
+
 			if tmp_health == "critical" {
-				state.Critical = append(state.Critical, fmt.Sprintf("Sensor \"%s\" is reported as critical", tmp_name))
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Sensor \"%s\" is reported as critical", tmp_name), component)
+			} else if reading_state == NAGIOS_CRITICAL {
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Sensor \"%s\" reports %s°C, critical range is %s", tmp_name, reading_str, tmp_crit.String()), component)
 			} else if tmp_health == "warning" {
-				state.Warning = append(state.Warning, fmt.Sprintf("Sensor \"%s\" is reported as warning", tmp_name))
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("Sensor \"%s\" is reported as warning", tmp_name), component)
+			} else if reading_state == NAGIOS_WARNING {
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("Sensor \"%s\" reports %s°C, warning range is %s", tmp_name, reading_str, tmp_warn.String()), component)
 			} else if tmp_health == "ok" {
-				state.Ok = append(state.Ok, fmt.Sprintf("Sensor \"%s\" is reported as ok", tmp_name))
+				ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("Sensor \"%s\" is reported as ok", tmp_name), component)
 			}
+
 			if tmp.ReadingCelsius != nil && *tmp.ReadingCelsius > 0 {
 				_cur := strconv.FormatInt(int64(*tmp.ReadingCelsius), 10)
 				_min := ""
@@ -127,13 +132,12 @@ func CheckThermal(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 					_max = strconv.FormatInt(int64(*tmp.MaxReadingRangeTemp), 10)
 				}
 
-				// we report _upper_ ranges but _lower_ ranges also exist
-				if tmp.UpperThresholdNonCritical != nil && *tmp.UpperThresholdNonCritical > 0 {
-					_min = strconv.FormatInt(int64(*tmp.UpperThresholdNonCritical), 10)
+				if tmp_warn != nil {
+					_wrn = tmp_warn.String()
 				}
 
-				if tmp.UpperThresholdCritical != nil && *tmp.UpperThresholdCritical > 0 {
-					_max = strconv.FormatInt(int64(*tmp.UpperThresholdCritical), 10)
+				if tmp_crit != nil {
+					_crt = tmp_crit.String()
 				}
 
 				label := fmt.Sprintf("temperature_%s", tmp_name)
@@ -148,3 +152,42 @@ func CheckThermal(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 
 	return state, nil
 }
+
+// ThermalCheck - check temperature sensors
+type ThermalCheck struct {
+	ThresholdFlags
+	enabled bool
+}
+
+func init() {
+	RegisterCheck(&ThermalCheck{})
+}
+
+func (c *ThermalCheck) Name() string {
+	return "thermal"
+}
+
+func (c *ThermalCheck) Usage() (string, string) {
+	return "", "Check thermal subsystem"
+}
+
+func (c *ThermalCheck) RegisterFlags() {
+	c.RegisterThresholdFlags(c.Name())
+	flag.BoolVar(&c.enabled, "check-"+c.Name(), false, "Check thermal status")
+}
+
+func (c *ThermalCheck) Requested() bool {
+	return c.enabled
+}
+
+func (c *ThermalCheck) Options() []CheckOption {
+	return c.ThresholdOptions(c.Name(), "degree Celsius")
+}
+
+func (c *ThermalCheck) Validate() error {
+	return c.ParseThresholdFlags(c.Name())
+}
+
+func (c *ThermalCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckThermal(rf, opts.ChassisId, c.Warning, c.Critical, opts.Rules)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckThermal(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_OK,
+			messages: []string{"Sensor \"01-Inlet Ambient\" is reported as ok", "Sensor \"02-CPU 1\" is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			rc:       NAGIOS_OK,
+			messages: []string{"Sensor \"System Board Inlet Temp\" is reported as ok", "Sensor \"CPU1 Temp\" is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Dell iDRAC, all chassis skips enclosure without thermal data",
+			profile:  "dell_idrac",
+			member:   ALL_MEMBERS,
+			rc:       NAGIOS_OK,
+			messages: []string{"Chassis System.Embedded.1: Sensor \"CPU1 Temp\" is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Dell iDRAC, enclosure without thermal data",
+			profile:  "dell_idrac",
+			member:   "Enclosure.Internal.0-1:RAID.Integrated.1-1",
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"No Thermal endpoint defined for chassis with ID Enclosure.Internal.0-1:RAID.Integrated.1-1"},
+			perfdata: 0,
+			err:      true,
+		},
+		{
+			name:     "Dell iDRAC, unknown chassis",
+			profile:  "dell_idrac",
+			member:   "System.Embedded.2",
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"Chassis with ID System.Embedded.2 not found"},
+			perfdata: 0,
+			err:      true,
+		},
+		{
+			name:     "Lenovo XCC",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"Sensor \"CPU 1 Temp\" is reported as warning", "Sensor \"Ambient Temp\" is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Supermicro",
+			profile:  "supermicro",
+			rc:       NAGIOS_OK,
+			messages: []string{"Sensor \"CPU Temp\" is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:    "Lower critical threshold of 0°C reported by the management board",
+			profile: "supermicro",
+			patch: map[string]string{"/redfish/v1/Chassis/1/Thermal": `{"Temperatures": [{
+				"Name": "Exhaust Temp", "ReadingCelsius": -3, "LowerThresholdCritical": 0, "UpperThresholdCritical": 90,
+				"Status": {"Health": "OK", "State": "Enabled"}}]}`},
+			rc:       NAGIOS_CRITICAL,
+			messages: []string{"Sensor \"Exhaust Temp\" reports -3°C, critical range is 90"},
+			perfdata: -1,
+		},
+		{
+			name:     "Thermal endpoint not found",
+			profile:  "hpe_ilo",
+			patch:    map[string]string{"/redfish/v1/Chassis/1/Thermal/": ""},
+			rc:       NAGIOS_UNKNOWN,
+			perfdata: 0,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckThermal(rf, member, nil, nil, nil)
+	})
+}
+
+func TestCheckThermalThresholds(t *testing.T) {
+	warn, _ := ParseRange("~:30")
+	crit, _ := ParseRange("~:50")
+
+	runCheckTests(t, []checkTestCase{
+		{
+			name:    "Supermicro",
+			profile: "supermicro",
+			rc:      NAGIOS_CRITICAL,
+			messages: []string{
+				"Sensor \"CPU Temp\" reports 55°C, critical range is ~:50",
+				"Sensor \"System Temp\" reports 33°C, warning range is ~:30",
+			},
+			perfdata: 2,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckThermal(rf, member, warn, crit, nil)
+	})
+
+	rule, err := parseThresholdRule(`thermal "CPU*" ~:60 ~:70`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := &ThresholdRules{Rules: []ThresholdRule{rule}}
+
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "Supermicro, rule overrides command line thresholds",
+			profile:  "supermicro",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"Sensor \"CPU Temp\" is reported as ok", "Sensor \"System Temp\" reports 33°C, warning range is ~:30"},
+			perfdata: 2,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckThermal(rf, member, warn, crit, rules)
+	})
+}
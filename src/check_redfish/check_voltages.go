@@ -2,15 +2,20 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	redfish "git.ypbind.de/repository/go-redfish.git"
 	"strconv"
 	"strings"
 )
 
-func CheckVoltages(rf redfish.Redfish, cha_id string) (NagiosState, error) {
-	var chassis_data *redfish.ChassisData
-	var found bool
+func CheckVoltages(rf RedfishClient, cha_id string, warn *Range, crit *Range, rules *ThresholdRules) (NagiosState, error) {
+	return CheckEachChassis(rf, cha_id, func(rf RedfishClient, chassis_data *redfish.ChassisData) (NagiosState, error) {
+		return checkVoltagesChassis(rf, chassis_data, warn, crit, rules)
+	})
+}
+
+func checkVoltagesChassis(rf RedfishClient, chassis_data *redfish.ChassisData, warn *Range, crit *Range, rules *ThresholdRules) (NagiosState, error) {
 	var state = NagiosState{
 		Critical: make([]string, 0),
 		Warning:  make([]string, 0),
@@ -18,43 +23,12 @@ func CheckVoltages(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 		Unknown:  make([]string, 0),
 		PerfData: make([]string, 0),
 	}
-
-	// no system specified, pick the first reported system
-	if cha_id == "" {
-		cha_epl, err := rf.GetChassis()
-		if err != nil {
-			return state, err
-		}
-
-		// should never happen
-		if len(cha_epl) == 0 {
-			state.Unknown = append(state.Unknown, "No chassis  endpoint reported at all")
-			return state, errors.New("BUG: No chassis endpoint reported at all")
-		}
-
-		chassis_data, err = rf.GetChassisData(cha_epl[0])
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-	} else {
-		cha_data_map, err := rf.MapChassisById()
-		if err != nil {
-			state.Unknown = append(state.Unknown, err.Error())
-			return state, err
-		}
-
-		chassis_data, found = cha_data_map[cha_id]
-		if !found {
-			state.Unknown = append(state.Unknown, fmt.Sprintf("Chassis with ID %s not found", cha_id))
-			return state, errors.New(fmt.Sprintf("Chassis with ID %s not found", cha_id))
-		}
-	}
+	var cha_id = ChassisId(chassis_data)
 
 	// check for "Power" endpoint
 	if chassis_data.Power == nil {
 		state.Unknown = append(state.Unknown, fmt.Sprintf("No Power endpoint defined for chassis with ID %s", cha_id))
-		return state, errors.New(fmt.Sprintf("No Power endpoint defined for chassis with ID %s", cha_id))
+		return state, MissingEndpointError{Message: fmt.Sprintf("No Power endpoint defined for chassis with ID %s", cha_id)}
 	}
 
 	if chassis_data.Power.Id == nil || *chassis_data.Power.Id == "" {
@@ -74,6 +48,13 @@ func CheckVoltages(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 			vlt_name = *vlt.Name
 		}
 
+		vlt_warn, vlt_crit := rules.Thresholds("voltages", vlt_name, warn, crit)
+
+		// thresholds reported by the management board are used unless the user supplied thresholds
+		vlt_warn, vlt_crit = BoardThresholds(vlt_warn, vlt_crit,
+			vlt.LowerThresholdNonCritical, vlt.LowerThresholdCritical,
+			vlt.UpperThresholdNonCritical, vlt.UpperThresholdCritical)
+
 		if vlt.Status.State == nil || *vlt.Status.State == "" {
 			continue
 		}
@@ -104,21 +85,51 @@ func CheckVoltages(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 			            But Voltages.State is only Enabled/Disabled/Absent
 		*/
 		if vlt_state == "enabled" {
+			component := ComponentState{
+				Name:     vlt_name,
+				Type:     "voltage",
+				Health:   *vlt.Status.Health,
+				Unit:     "V",
+				Warning:  vlt_warn.String(),
+				Critical: vlt_crit.String(),
+			}
+
+			// evaluate reading against thresholds
+			reading_state := NAGIOS_OK
+			reading_str := ""
+			if vlt.ReadingVolts != nil {
+				reading := float64(*vlt.ReadingVolts)
+				component.Reading = &reading
+				reading_state = ThresholdState(reading, vlt_warn, vlt_crit)
+				reading_str = strconv.FormatFloat(reading, 'f', -1, 64)
+			}
+
 			if vlt_health == "critical" {
-				state.Critical = append(state.Critical, fmt.Sprintf("Voltage %s is reported as critical", vlt_name))
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Voltage %s is reported as critical", vlt_name), component)
+			} else if reading_state == NAGIOS_CRITICAL {
+				ReportComponent(&state, NAGIOS_CRITICAL, fmt.Sprintf("Voltage %s reports %sV, critical range is %s", vlt_name, reading_str, vlt_crit.String()), component)
 			} else if vlt_health == "warning" {
-				state.Warning = append(state.Warning, fmt.Sprintf("Voltage %s is reported as warning", vlt_name))
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("Voltage %s is reported as warning", vlt_name), component)
+			} else if reading_state == NAGIOS_WARNING {
+				ReportComponent(&state, NAGIOS_WARNING, fmt.Sprintf("Voltage %s reports %sV, warning range is %s", vlt_name, reading_str, vlt_warn.String()), component)
 			} else if vlt_health == "ok" {
-				state.Ok = append(state.Ok, fmt.Sprintf("Voltage %s is reported as ok", vlt_name))
+				ReportComponent(&state, NAGIOS_OK, fmt.Sprintf("Voltage %s is reported as ok", vlt_name), component)
 			}
 
 			if vlt.ReadingVolts != nil && *vlt.ReadingVolts > 0.0 {
-				// XXX: Is there a way to handle upper/lower warning/critical thresholds (LowerThresholdNonCritical, LowerThresholdCritical
-				//      UpperThresholdNonCritical and UpperThresholdCritical) ?
-
 				_cur := strconv.FormatFloat(*vlt.ReadingVolts, 'f', -1, 32)
 				_min := ""
 				_max := ""
+				_wrn := ""
+				_crt := ""
+
+				if vlt_warn != nil {
+					_wrn = vlt_warn.String()
+				}
+
+				if vlt_crit != nil {
+					_crt = vlt_crit.String()
+				}
 
 				if vlt.MinReadingRange != nil && *vlt.MinReadingRange > 0.0 {
 					_min = strconv.FormatFloat(*vlt.MinReadingRange, 'f', -1, 32)
@@ -128,7 +139,7 @@ func CheckVoltages(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 					_max = strconv.FormatFloat(*vlt.MaxReadingRange, 'f', -1, 32)
 				}
 				label := fmt.Sprintf("voltage_%s", vlt_name)
-				perfdata, err := MakePerfDataString(label, _cur, nil, nil, nil, &_min, &_max)
+				perfdata, err := MakePerfDataString(label, _cur, nil, &_wrn, &_crt, &_min, &_max)
 				if err == nil {
 					state.PerfData = append(state.PerfData, perfdata)
 				}
@@ -137,3 +148,42 @@ func CheckVoltages(rf redfish.Redfish, cha_id string) (NagiosState, error) {
 	}
 	return state, nil
 }
+
+// VoltagesCheck - check voltage readings
+type VoltagesCheck struct {
+	ThresholdFlags
+	enabled bool
+}
+
+func init() {
+	RegisterCheck(&VoltagesCheck{})
+}
+
+func (c *VoltagesCheck) Name() string {
+	return "voltages"
+}
+
+func (c *VoltagesCheck) Usage() (string, string) {
+	return "", "Check system voltage readings"
+}
+
+func (c *VoltagesCheck) RegisterFlags() {
+	c.RegisterThresholdFlags(c.Name())
+	flag.BoolVar(&c.enabled, "check-"+c.Name(), false, "Check voltages")
+}
+
+func (c *VoltagesCheck) Requested() bool {
+	return c.enabled
+}
+
+func (c *VoltagesCheck) Options() []CheckOption {
+	return c.ThresholdOptions(c.Name(), "Volt")
+}
+
+func (c *VoltagesCheck) Validate() error {
+	return c.ParseThresholdFlags(c.Name())
+}
+
+func (c *VoltagesCheck) Run(rf RedfishClient, opts CheckOptions) (NagiosState, error) {
+	return CheckVoltages(rf, opts.ChassisId, c.Warning, c.Critical, opts.Rules)
+}
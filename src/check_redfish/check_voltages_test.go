@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCheckVoltages(t *testing.T) {
+	runCheckTests(t, []checkTestCase{
+		{
+			name:     "HPE iLO reports no voltages",
+			profile:  "hpe_ilo",
+			rc:       NAGIOS_UNKNOWN,
+			perfdata: 0,
+		},
+		{
+			name:     "Dell iDRAC",
+			profile:  "dell_idrac",
+			rc:       NAGIOS_OK,
+			messages: []string{"Voltage CPU1 VCORE PG is reported as ok", "Voltage PS1 Voltage 1 is reported as ok"},
+			perfdata: 3,
+		},
+		{
+			name:     "Lenovo XCC",
+			profile:  "lenovo_xcc",
+			rc:       NAGIOS_OK,
+			messages: []string{"Voltage CMOS Battery is reported as ok", "Voltage SysBrd 12V is reported as ok"},
+			perfdata: 2,
+		},
+		{
+			name:     "Supermicro",
+			profile:  "supermicro",
+			rc:       NAGIOS_WARNING,
+			messages: []string{"Voltage 12V is reported as ok", "Voltage VBAT reports 2.6V, warning range is 2.7:3.5"},
+			perfdata: 2,
+		},
+		{
+			name:     "Dell iDRAC, enclosure without power data",
+			profile:  "dell_idrac",
+			member:   "Enclosure.Internal.0-1:RAID.Integrated.1-1",
+			rc:       NAGIOS_UNKNOWN,
+			messages: []string{"No Power endpoint defined for chassis with ID Enclosure.Internal.0-1:RAID.Integrated.1-1"},
+			perfdata: 0,
+			err:      true,
+		},
+	}, func(rf RedfishClient, member string) (NagiosState, error) {
+		return CheckVoltages(rf, member, nil, nil, nil)
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// CONFIG_DEFAULT_SECTION - section of the configuration file with defaults for all hosts
+const CONFIG_DEFAULT_SECTION string = "default"
+
+// ApplyConfigFile sets options that haven't been set on the command line from the sections [default] and [<host>]
+// of the configuration file f. Keys are the names of the command line options. Because -host names the section,
+// the host name is taken from the "host" key of the section if present. The host name to connect to is returned.
+func ApplyConfigFile(f string, host string) (string, error) {
+	var cmdline = make(map[string]bool)
+
+	ini, err := ReadIniFile(f)
+	if err != nil {
+		return host, err
+	}
+
+	flag.Visit(func(fl *flag.Flag) {
+		cmdline[fl.Name] = true
+	})
+
+	// keys outside of a section are treated as defaults too
+	sections := []string{"", CONFIG_DEFAULT_SECTION}
+	if host != "" && host != CONFIG_DEFAULT_SECTION {
+		sections = append(sections, host)
+	}
+
+	for _, name := range sections {
+		section, found := ini[name]
+		if !found {
+			continue
+		}
+
+		for _, key := range section.Keys {
+			value := section.Values[key]
+
+			if key == "host" {
+				if name != "" && name != CONFIG_DEFAULT_SECTION {
+					host = value
+				}
+				continue
+			}
+
+			if key == "config" || key == "listen" {
+				return host, errors.New(fmt.Sprintf("%s: Option %s can't be set in the configuration file", f, key))
+			}
+
+			fl := flag.Lookup(key)
+			if fl == nil {
+				return host, errors.New(fmt.Sprintf("%s: Unknown option %s in section [%s]", f, key, name))
+			}
+
+			// command line options take precedence
+			if cmdline[key] {
+				continue
+			}
+
+			// keys without value enable boolean options
+			if value == "" && isBoolFlag(fl) {
+				value = "true"
+			}
+
+			err = fl.Value.Set(value)
+			if err != nil {
+				return host, errors.New(fmt.Sprintf("%s: Invalid value %s for option %s in section [%s]: %s", f, value, key, name, err.Error()))
+			}
+		}
+	}
+
+	return host, nil
+}
+
+func isBoolFlag(fl *flag.Flag) bool {
+	b, ok := fl.Value.(interface {
+		IsBoolFlag() bool
+	})
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIniFile(t *testing.T, name string, content string) string {
+	f := filepath.Join(t.TempDir(), name)
+	err := ioutil.WriteFile(f, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// setCommandLine replaces the command line flags by a new set of options parsed from args
+func setCommandLine(t *testing.T, args []string) map[string]*string {
+	var values = make(map[string]*string)
+
+	saved := flag.CommandLine
+	t.Cleanup(func() {
+		flag.CommandLine = saved
+	})
+
+	flag.CommandLine = flag.NewFlagSet("check_redfish", flag.ContinueOnError)
+	for _, name := range []string{"user", "password", "chassis-id", "config", "listen"} {
+		values[name] = flag.String(name, "", "")
+	}
+	values["timeout"] = new(string)
+	flag.Var(digitsValue{values["timeout"]}, "timeout", "")
+	flag.Bool("insecure-ssl", false, "")
+
+	err := flag.CommandLine.Parse(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+// digitsValue - flag.Value rejecting anything but digits, to test invalid values
+type digitsValue struct {
+	value *string
+}
+
+func (v digitsValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return *v.value
+}
+
+func (v digitsValue) Set(s string) error {
+	if strings.Trim(s, "0123456789") != "" || s == "" {
+		return flag.ErrHelp
+	}
+	*v.value = s
+	return nil
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	f := writeIniFile(t, "check_redfish.ini", `
+chassis-id = Outside
+
+[default]
+user = monitoring
+password = "default secret"
+timeout = 30
+
+[bmc1]
+host = bmc1.example.com
+password = 'bmc1 secret'
+timeout = 60
+insecure-ssl
+
+[bmc2]
+user = admin
+`)
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		host     string
+		expected map[string]string
+		insecure bool
+		target   string
+	}{
+		{
+			name:     "host section overrides defaults",
+			host:     "bmc1",
+			expected: map[string]string{"user": "monitoring", "password": "bmc1 secret", "timeout": "60", "chassis-id": "Outside"},
+			insecure: true,
+			target:   "bmc1.example.com",
+		},
+		{
+			name:     "command line overrides host section",
+			args:     []string{"-password", "cmdline secret", "-timeout", "5"},
+			host:     "bmc1",
+			expected: map[string]string{"user": "monitoring", "password": "cmdline secret", "timeout": "5"},
+			insecure: true,
+			target:   "bmc1.example.com",
+		},
+		{
+			name:     "section without host key",
+			host:     "bmc2",
+			expected: map[string]string{"user": "admin", "password": "default secret", "timeout": "30"},
+			target:   "bmc2",
+		},
+		{
+			name:     "host without section",
+			host:     "bmc3.example.com",
+			expected: map[string]string{"user": "monitoring", "password": "default secret", "timeout": "30"},
+			target:   "bmc3.example.com",
+		},
+	} {
+		values := setCommandLine(t, tc.args)
+
+		target, err := ApplyConfigFile(f, tc.host)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err.Error())
+			continue
+		}
+
+		if target != tc.target {
+			t.Errorf("%s: expected host %s but got %s", tc.name, tc.target, target)
+		}
+		for key, value := range tc.expected {
+			if *values[key] != value {
+				t.Errorf("%s: expected %s for %s but got %s", tc.name, value, key, *values[key])
+			}
+		}
+		if (flag.Lookup("insecure-ssl").Value.String() == "true") != tc.insecure {
+			t.Errorf("%s: expected insecure-ssl %v", tc.name, tc.insecure)
+		}
+	}
+}
+
+func TestApplyConfigFileInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		err     string
+	}{
+		{content: "[default]\nunknown = 1\n", err: "Unknown option unknown in section [default]"},
+		{content: "[bmc1]\nconfig = other.ini\n", err: "Option config can't be set in the configuration file"},
+		{content: "[default]\nlisten = :9000\n", err: "Option listen can't be set in the configuration file"},
+		{content: "[default]\ntimeout = forever\n", err: "Invalid value forever for option timeout in section [default]"},
+		{content: "[default\n", err: "line 1: Invalid section [default"},
+	} {
+		setCommandLine(t, nil)
+		f := writeIniFile(t, "check_redfish.ini", tc.content)
+
+		_, err := ApplyConfigFile(f, "bmc1")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%q: expected error %q but got %v", tc.content, tc.err, err)
+		}
+	}
+}
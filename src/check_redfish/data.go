@@ -8,10 +8,32 @@ const (
 	NAGIOS_UNKNOWN
 )
 
+// NAGIOS_STATE_NAMES - names of the exit codes
+var NAGIOS_STATE_NAMES = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
 type NagiosState struct {
 	Critical []string
 	Warning  []string
 	Ok       []string
 	Unknown  []string
 	PerfData []string
+	// Components - details of the components (sensors, fans, power supplies, ...) reported by a check
+	Components []ComponentState
+	// Retries - number of requests retried because the management board was busy or unreachable
+	Retries int
+}
+
+// ComponentState - state of a single component as reported by a check
+type ComponentState struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Member       string   `json:"member,omitempty"`
+	State        string   `json:"state"`
+	Health       string   `json:"health,omitempty"`
+	Reading      *float64 `json:"reading,omitempty"`
+	Unit         string   `json:"unit,omitempty"`
+	Warning      string   `json:"warning,omitempty"`
+	Critical     string   `json:"critical,omitempty"`
+	SerialNumber string   `json:"serial_number,omitempty"`
+	Message      string   `json:"message"`
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExporterMetric - metric exposed by the exporter, component readings of type Type are reported as Name
+type ExporterMetric struct {
+	Name string
+	Help string
+	Type string
+}
+
+var exporter_metrics = []ExporterMetric{
+	{Name: "redfish_temperature_celsius", Help: "Temperature reading in degree Celsius", Type: "temperature"},
+	{Name: "redfish_fan_rpm", Help: "Fan reading in RPM", Type: "fan"},
+	{Name: "redfish_voltage_volts", Help: "Voltage reading in Volt", Type: "voltage"},
+	{Name: "redfish_psu_power_output_watts", Help: "Last power output of power supply in Watt", Type: "psu"},
+}
+
+// health states reported as enum, health strings not listed here will be added as additional state
+var exporter_health_states = []string{"ok", "warning", "critical"}
+
+// RunExporter serves metrics of the management board selected by the target parameter of /metrics requests.
+// All settings except the host name are taken from template.
+func RunExporter(listen string, template BmcClient) error {
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+
+		rf := template
+		rf.Hostname = target
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, CollectMetrics(&rf))
+	})
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "check_redfish exporter, metrics are available at /metrics?target=<host>\n")
+	})
+
+	return http.ListenAndServe(listen, nil)
+}
+
+// CollectMetrics logs in to the management board, runs the thermal, fan, voltage, PSU and general health checks
+// and returns the readings of the components in the Prometheus text format
+func CollectMetrics(rf *BmcClient) string {
+	var result string
+	var state = NagiosState{
+		Critical: make([]string, 0),
+		Warning:  make([]string, 0),
+		Ok:       make([]string, 0),
+		Unknown:  make([]string, 0),
+		PerfData: make([]string, 0),
+	}
+	var up = 1
+
+	start := time.Now()
+
+	// every scrape uses a new client, its connections would be kept until the management board closes them
+	defer rf.Close()
+
+	err := rf.Initialise()
+	if err == nil {
+		err = rf.Login()
+	}
+
+	if err != nil {
+		up = 0
+	} else {
+		defer rf.Logout()
+
+		// thermal and fans (as well as voltages and PSU) use the same resources
+		client := NewCachedClient(rf)
+
+		s, _ := CheckThermal(client, ALL_MEMBERS, nil, nil, nil)
+		state = MergeNagiosState(state, s)
+		s, _ = CheckFans(client, ALL_MEMBERS, nil, nil, nil)
+		state = MergeNagiosState(state, s)
+		s, _ = CheckVoltages(client, ALL_MEMBERS, nil, nil, nil)
+		state = MergeNagiosState(state, s)
+		s, _ = CheckPsu(client, ALL_MEMBERS, 1, 1)
+		state = MergeNagiosState(state, s)
+		s, _ = CheckGeneralHealth(client, ALL_MEMBERS)
+		state = MergeNagiosState(state, s)
+	}
+
+	result += "# HELP redfish_up Login on the management board was successful\n"
+	result += "# TYPE redfish_up gauge\n"
+	result += fmt.Sprintf("redfish_up %d\n", up)
+
+	indexes := componentIndexes(state.Components)
+
+	for _, m := range exporter_metrics {
+		result += fmt.Sprintf("# HELP %s %s\n", m.Name, m.Help)
+		result += fmt.Sprintf("# TYPE %s gauge\n", m.Name)
+		for i, c := range state.Components {
+			if c.Type != m.Type || c.Reading == nil {
+				continue
+			}
+			result += fmt.Sprintf("%s{%s} %g\n", m.Name, metricLabels(c, indexes[i], nil), *c.Reading)
+		}
+	}
+
+	result += "# HELP redfish_health Health reported for the component\n"
+	result += "# TYPE redfish_health gauge\n"
+	for i, c := range state.Components {
+		if c.Health == "" {
+			continue
+		}
+
+		health := strings.ToLower(c.Health)
+		states := append([]string{}, exporter_health_states...)
+		if !containsString(states, health) {
+			states = append(states, health)
+		}
+
+		for _, h := range states {
+			value := 0
+			if h == health {
+				value = 1
+			}
+			result += fmt.Sprintf("redfish_health{%s} %d\n", metricLabels(c, indexes[i], map[string]string{"health": h}), value)
+		}
+	}
+
+	result += "# HELP redfish_unknown_results Number of results with unknown state, e.g. missing endpoints\n"
+	result += "# TYPE redfish_unknown_results gauge\n"
+	result += fmt.Sprintf("redfish_unknown_results %d\n", len(state.Unknown))
+
+	result += "# HELP redfish_scrape_duration_seconds Time spent to collect the metrics\n"
+	result += "# TYPE redfish_scrape_duration_seconds gauge\n"
+	result += fmt.Sprintf("redfish_scrape_duration_seconds %g\n", time.Since(start).Seconds())
+
+	return result
+}
+
+// componentIndexes returns for each component the number of preceding components with the same labels,
+// e.g. several sensors named "<unnamed sensor>"
+func componentIndexes(components []ComponentState) []int {
+	var seen = make(map[string]int)
+	var result = make([]int, len(components))
+
+	for i, c := range components {
+		key := metricLabels(c, 0, nil)
+		result[i] = seen[key]
+		seen[key]++
+	}
+	return result
+}
+
+// metricLabels returns the labels of component c, index (if not 0) distinguishes components with the same
+// labels, Prometheus rejects the whole scrape if a series is duplicated
+func metricLabels(c ComponentState, index int, extra map[string]string) string {
+	labels := []string{
+		fmt.Sprintf("member=\"%s\"", escapeLabelValue(c.Member)),
+		fmt.Sprintf("name=\"%s\"", escapeLabelValue(c.Name)),
+		fmt.Sprintf("type=\"%s\"", escapeLabelValue(c.Type)),
+	}
+
+	if c.SerialNumber != "" {
+		labels = append(labels, fmt.Sprintf("serial_number=\"%s\"", escapeLabelValue(c.SerialNumber)))
+	}
+
+	if index > 0 {
+		labels = append(labels, fmt.Sprintf("index=\"%d\"", index))
+	}
+
+	keys := make([]string, 0)
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", k, escapeLabelValue(extra[k])))
+	}
+
+	return strings.Join(labels, ",")
+}
+
+func escapeLabelValue(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "\"", "\\\"", -1)
+	return strings.Replace(s, "\n", "\\n", -1)
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
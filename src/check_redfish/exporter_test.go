@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectMetrics(t *testing.T) {
+	for _, profile := range []string{"hpe_ilo", "dell_idrac", "lenovo_xcc", "supermicro"} {
+		t.Run(profile, func(t *testing.T) {
+			m := NewMockBmc(t, profile, nil)
+			verifyMetrics(t, CollectMetrics(m.NewClient()))
+
+			if m.Sessions() != 0 {
+				t.Errorf("Session has not been closed after the scrape")
+			}
+		})
+	}
+}
+
+func TestCollectMetricsUnnamedSensors(t *testing.T) {
+	m := NewMockBmc(t, "supermicro", map[string]string{"/redfish/v1/Chassis/1/Thermal": `{"Temperatures": [
+		{"ReadingCelsius": 30, "Status": {"Health": "OK", "State": "Enabled"}},
+		{"ReadingCelsius": 40, "Status": {"Health": "OK", "State": "Enabled"}}]}`})
+
+	metrics := verifyMetrics(t, CollectMetrics(m.NewClient()))
+
+	for _, series := range []string{
+		`redfish_temperature_celsius{member="1",name="<unnamed sensor>",type="temperature"} 30`,
+		`redfish_temperature_celsius{member="1",name="<unnamed sensor>",type="temperature",index="1"} 40`,
+	} {
+		if !containsString(metrics, series) {
+			t.Errorf("Series %s not found in %q", series, metrics)
+		}
+	}
+}
+
+// verifyMetrics checks that the management board was up and that no series is duplicated, returns the series
+func verifyMetrics(t *testing.T, metrics string) []string {
+	var seen = make(map[string]bool)
+	var result = make([]string, 0)
+
+	for _, line := range strings.Split(strings.TrimSpace(metrics), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+
+		series := line[:strings.LastIndex(line, " ")]
+		if seen[series] {
+			t.Errorf("Duplicate series %s", series)
+		}
+		seen[series] = true
+	}
+
+	if !containsString(result, "redfish_up 1") {
+		t.Errorf("Management board is not up: %q", result)
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// default locations of the plugins.ini file, see https://www.monitoring-plugins.org/doc/extra-opts.html
+var extra_opts_default_files = []string{
+	"/etc/nagios/plugins.ini",
+	"/usr/local/nagios/etc/plugins.ini",
+	"/usr/local/etc/nagios/plugins.ini",
+	"/etc/opt/nagios/plugins.ini",
+	"/etc/nagios-plugins.ini",
+	"/usr/local/etc/nagios-plugins.ini",
+	"/etc/opt/nagios-plugins.ini",
+}
+
+// ExpandExtraOpts replaces --extra-opts=[section][@file] in args by the options read from section of file.
+// Options read from the file are put in front of the command line, so options on the command line take precedence.
+func ExpandExtraOpts(args []string) ([]string, error) {
+	var extra = make([]string, 0)
+	var result = make([]string, 0)
+
+	for _, arg := range args {
+		opt := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") || (opt != "extra-opts" && !strings.HasPrefix(opt, "extra-opts=")) {
+			result = append(result, arg)
+			continue
+		}
+
+		var spec string
+		if strings.HasPrefix(opt, "extra-opts=") {
+			spec = strings.TrimPrefix(opt, "extra-opts=")
+		}
+
+		opts, err := readExtraOpts(spec)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, opts...)
+	}
+
+	return append(extra, result...), nil
+}
+
+func readExtraOpts(spec string) ([]string, error) {
+	var result = make([]string, 0)
+	var section string
+	var file string
+	var err error
+
+	/*
+	   Format of the --extra-opts argument:
+
+	       --extra-opts                  section check_redfish of the default ini file
+	       --extra-opts=section          section of the default ini file
+	       --extra-opts=@file            section check_redfish of file
+	       --extra-opts=section@file     section of file
+	*/
+	splitted := strings.SplitN(spec, "@", 2)
+	section = splitted[0]
+	if len(splitted) == 2 {
+		file = splitted[1]
+	}
+
+	if section == "" {
+		section = filepath.Base(os.Args[0])
+	}
+
+	if file == "" {
+		file, err = findExtraOptsFile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ini, err := ReadIniFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	values, found := ini[section]
+	if !found {
+		return nil, errors.New(fmt.Sprintf("Section %s not found in %s", section, file))
+	}
+
+	for _, key := range values.Keys {
+		if key == "extra-opts" {
+			return nil, errors.New(fmt.Sprintf("%s: extra-opts can't be nested", file))
+		}
+
+		if values.Values[key] == "" {
+			// options without value, e.g. insecure-ssl
+			result = append(result, "-"+key)
+		} else {
+			result = append(result, "-"+key+"="+values.Values[key])
+		}
+	}
+
+	return result, nil
+}
+
+// findExtraOptsFile returns the first plugins.ini found in the directories of NAGIOS_CONFIG_PATH or the default locations
+func findExtraOptsFile() (string, error) {
+	var candidates = make([]string, 0)
+
+	cfg_path := os.Getenv("NAGIOS_CONFIG_PATH")
+	if cfg_path != "" {
+		for _, dir := range strings.Split(cfg_path, ":") {
+			candidates = append(candidates, filepath.Join(dir, "plugins.ini"), filepath.Join(dir, "nagios-plugins.ini"))
+		}
+	}
+	candidates = append(candidates, extra_opts_default_files...)
+
+	for _, f := range candidates {
+		_, err := os.Stat(f)
+		if err == nil {
+			return f, nil
+		}
+	}
+
+	return "", errors.New("No ini file for --extra-opts found")
+}
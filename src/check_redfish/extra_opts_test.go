@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandExtraOpts(t *testing.T) {
+	f := writeIniFile(t, "plugins.ini", `
+[check_redfish]
+user = monitoring
+insecure-ssl
+
+[bmc1]
+host = bmc1.example.com
+password = "bmc1 secret"
+`)
+
+	saved := os.Args[0]
+	os.Args[0] = "/usr/lib/nagios/plugins/check_redfish"
+	defer func() {
+		os.Args[0] = saved
+	}()
+
+	for _, tc := range []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			args:     []string{"-extra-opts=bmc1@" + f, "-check", "fans"},
+			expected: []string{"-host=bmc1.example.com", "-password=bmc1 secret", "-check", "fans"},
+		},
+		{
+			// section defaults to the name of the plugin
+			args:     []string{"-check", "fans", "--extra-opts=@" + f},
+			expected: []string{"-user=monitoring", "-insecure-ssl", "-check", "fans"},
+		},
+		{
+			// options of the command line are kept behind the options from the file, so they take precedence
+			args:     []string{"-user", "admin", "-extra-opts=@" + f, "-extra-opts=bmc1@" + f},
+			expected: []string{"-user=monitoring", "-insecure-ssl", "-host=bmc1.example.com", "-password=bmc1 secret", "-user", "admin"},
+		},
+		{
+			args:     []string{"-host", "bmc1", "-extra-optsx"},
+			expected: []string{"-host", "bmc1", "-extra-optsx"},
+		},
+	} {
+		result, err := ExpandExtraOpts(tc.args)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tc.args, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%q: expected %q but got %q", tc.args, tc.expected, result)
+		}
+	}
+}
+
+func TestExpandExtraOptsConfigPath(t *testing.T) {
+	empty := t.TempDir()
+	f := writeIniFile(t, "nagios-plugins.ini", "[bmc1]\nuser = monitoring\n")
+
+	// the first directory of NAGIOS_CONFIG_PATH containing plugins.ini or nagios-plugins.ini is used
+	t.Setenv("NAGIOS_CONFIG_PATH", empty+":"+filepath.Dir(f))
+
+	result, err := ExpandExtraOpts([]string{"--extra-opts=bmc1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(result, []string{"-user=monitoring"}) {
+		t.Errorf("Unexpected options %q", result)
+	}
+}
+
+func TestExpandExtraOptsInvalid(t *testing.T) {
+	f := writeIniFile(t, "plugins.ini", "[nested]\nextra-opts = other@plugins.ini\n")
+
+	for _, tc := range []struct {
+		arg string
+		err string
+	}{
+		{arg: "-extra-opts=bmc1@" + f, err: "Section bmc1 not found in " + f},
+		{arg: "-extra-opts=nested@" + f, err: f + ": extra-opts can't be nested"},
+		{arg: "-extra-opts=bmc1@" + f + ".missing", err: "no such file or directory"},
+	} {
+		_, err := ExpandExtraOpts([]string{tc.arg})
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error %q but got %v", tc.arg, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	redfish "git.ypbind.de/repository/go-redfish.git"
+	"strings"
+	"testing"
+)
+
+// FixtureClient - RedfishClient serving the documents of a profile without HTTP
+type FixtureClient struct {
+	Profile *MockProfile
+}
+
+// NewFixtureClient returns a client for profile, see loadPatchedProfile for patch
+func NewFixtureClient(t *testing.T, profile string, patch map[string]string) *FixtureClient {
+	return &FixtureClient{Profile: loadPatchedProfile(t, profile, patch)}
+}
+
+func (f *FixtureClient) get(endpoint string, v interface{}) error {
+	doc, found := f.Profile.Documents[endpoint]
+	if !found {
+		// accept the endpoint with or without trailing slash
+		if strings.HasSuffix(endpoint, "/") {
+			doc, found = f.Profile.Documents[strings.TrimSuffix(endpoint, "/")]
+		} else {
+			doc, found = f.Profile.Documents[endpoint+"/"]
+		}
+	}
+
+	if !found {
+		return errors.New(fmt.Sprintf("No fixture for %s", endpoint))
+	}
+	return json.Unmarshal(doc, v)
+}
+
+func (f *FixtureClient) members(endpoint func(serviceRoot) string) ([]string, error) {
+	var root serviceRoot
+	var coll collection
+	var result = make([]string, 0)
+
+	err := f.get("/redfish/v1/", &root)
+	if err != nil {
+		return result, err
+	}
+
+	err = f.get(endpoint(root), &coll)
+	if err != nil {
+		return result, err
+	}
+
+	for _, m := range coll.Members {
+		result = append(result, m.Id)
+	}
+	return result, nil
+}
+
+func (f *FixtureClient) GetChassis() ([]string, error) {
+	return f.members(func(root serviceRoot) string { return root.Chassis.Id })
+}
+
+func (f *FixtureClient) GetChassisData(endpoint string) (*redfish.ChassisData, error) {
+	var result redfish.ChassisData
+	return &result, f.get(endpoint, &result)
+}
+
+func (f *FixtureClient) MapChassisById() (map[string]*redfish.ChassisData, error) {
+	return MapChassisById(f)
+}
+
+func (f *FixtureClient) GetThermalData(endpoint string) (*redfish.ThermalData, error) {
+	var result redfish.ThermalData
+	return &result, f.get(endpoint, &result)
+}
+
+func (f *FixtureClient) GetPowerData(endpoint string) (*redfish.PowerData, error) {
+	var result redfish.PowerData
+	return &result, f.get(endpoint, &result)
+}
+
+func (f *FixtureClient) GetSystems() ([]string, error) {
+	return f.members(func(root serviceRoot) string { return root.Systems.Id })
+}
+
+func (f *FixtureClient) GetSystemData(endpoint string) (*redfish.SystemData, error) {
+	var result redfish.SystemData
+	return &result, f.get(endpoint, &result)
+}
+
+func (f *FixtureClient) MapSystemsById() (map[string]*redfish.SystemData, error) {
+	return MapSystemsById(f)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// interval between attempts to acquire a lock held by another process
+const LOCK_POLL_INTERVAL = 100 * time.Millisecond
+
+// FileLock - exclusive flock(2) lock of a file, released by Unlock or when the process exits
+type FileLock struct {
+	file *os.File
+}
+
+// TryLockFile locks the file f (created if missing) without waiting, returns nil if another process holds the lock.
+// Symbolic links aren't followed, so a link planted in the directory can't be used to create or lock other files.
+func TryLockFile(f string) (*FileLock, error) {
+	fd, err := os.OpenFile(f, os.O_RDONLY|os.O_CREATE|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(fd.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		fd.Close()
+		return nil, nil
+	}
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	return &FileLock{file: fd}, nil
+}
+
+// LockFile locks the file f (created if missing), waiting at most wait for other processes holding the lock
+func LockFile(f string, wait time.Duration) (*FileLock, error) {
+	give_up := time.Now().Add(wait)
+
+	for {
+		lock, err := TryLockFile(f)
+		if err != nil || lock != nil {
+			return lock, err
+		}
+
+		if time.Now().After(give_up) {
+			return nil, errors.New(fmt.Sprintf("Timed out after %s waiting for lock %s", wait.String(), f))
+		}
+		time.Sleep(LOCK_POLL_INTERVAL)
+	}
+}
+
+func (l *FileLock) Unlock() error {
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	return l.file.Close()
+}
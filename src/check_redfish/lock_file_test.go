@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := LockFile(f, time.Second)
+	if err != nil {
+		t.Fatalf("Can't lock %s: %s", f, err.Error())
+	}
+
+	other, err := TryLockFile(f)
+	if err != nil || other != nil {
+		t.Errorf("Lock held twice (error: %v)", err)
+	}
+
+	_, err = LockFile(f, 200*time.Millisecond)
+	if err == nil {
+		t.Errorf("Expected a timeout waiting for the lock")
+	}
+
+	lock.Unlock()
+
+	other, err = TryLockFile(f)
+	if err != nil || other == nil {
+		t.Fatalf("Lock has not been released (error: %v)", err)
+	}
+	other.Unlock()
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	redfish "git.ypbind.de/repository/go-redfish.git"
+	"net/url"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -14,44 +13,108 @@ func main() {
 	var user = flag.String("user", "", "Username for authentication")
 	var password = flag.String("password", "", "Password for authentication")
 	var password_file = flag.String("password-file", "", "Read password for authentication from file, password is exepected in the first line of the file")
+	var user_env = flag.String("user-env", "", "Read username for authentication from environment variable")
+	var user_command = flag.String("user-command", "", "Read username for authentication from the first line of the output of a command")
+	var password_env = flag.String("password-env", "", "Read password for authentication from environment variable")
+	var password_command = flag.String("password-command", "", "Read password for authentication from the first line of the output of a command")
+	var secret_timeout = flag.Uint("secret-timeout", 10, "Timeout in seconds for -user-command and -password-command")
 	var host = flag.String("host", "", "Hostname or IP address of management board")
 	var port = flag.Int("port", 0, "Port to connect to")
 	var insecure_ssl = flag.Bool("insecure-ssl", false, "Don't verifiy SSL certificate")
-	var chassis_id = flag.String("chassis-id", "", "Process data of specific chassis")
-	var system_id = flag.String("system-id", "", "Process data of specific system")
-	var check_installed_memory = flag.String("check-installed-memory", "", "Check installed memory")
-	var check_installed_cpus = flag.String("check-installed-cpus", "", "Check installed CPUs")
-	var check_thermal = flag.Bool("check-thermal", false, "Check thermal status")
-	var check_fans = flag.Bool("check-fans", false, "Check system fans")
-	var check_psu = flag.String("check-psu", "", "Check PSU")
-	var check_voltages = flag.Bool("check-voltages", false, "Check voltages")
-	var check_general = flag.Bool("check-general-health", true, "Check general health")
+	var ca_file = flag.String("ca-file", "", "Verify SSL certificate using the CA certificates from file")
+	var client_cert = flag.String("client-cert", "", "Client certificate for mutual TLS")
+	var client_key = flag.String("client-key", "", "Key of the client certificate for mutual TLS")
+	var proxy = flag.String("proxy", "", "Connect to the management board through HTTP or SOCKS5 proxy URL")
+	var fingerprint = flag.String("fingerprint", "", "Accept only the SSL certificate with this SHA256 fingerprint")
+	var chassis_id = flag.String("chassis-id", "", "Process data of specific chassis or \"all\" for every chassis")
+	var system_id = flag.String("system-id", "", "Process data of specific system or \"all\" for every system")
+	var output = flag.String("output", "short", "Output format: short, long or json")
+	var rules_file = flag.String("rules", "", "Read per sensor thresholds from file")
 	var timeout = flag.Uint("timeout", 60, "Connection timeout in seconds")
+	var retries = flag.Uint("retries", 0, "Number of retries if the management board is busy or unreachable")
+	var retry_delay = flag.Uint("retry-delay", 1, "Delay in seconds before the first retry, doubled for every further retry")
+	var deadline = flag.Uint("deadline", 0, "Overall timeout of the plugin run in seconds, 0 disables the overall timeout")
+	var listen = flag.String("listen", "", "Run as Prometheus exporter listening on address")
+	var config_file = flag.String("config", "", "Read options from configuration file")
+	var record = flag.String("record", "", "Store all responses of the management board in directory")
+	var replay = flag.String("replay", "", "Read responses from directory created by -record instead of connecting to the management board")
+	var session_cache = flag.String("session-cache", "", "Reuse Redfish sessions stored in directory")
+	var response_cache = flag.String("response-cache", "", "Share responses of the management board between runs using directory")
+	var response_cache_ttl = flag.Uint("response-cache-ttl", 60, "Time in seconds responses stored in -response-cache are used")
+	var max_sessions = flag.Uint("max-sessions", 0, "Maximal number of concurrent runs for a management board, 0 for no limit")
+	var max_sessions_wait = flag.Uint("max-sessions-wait", 30, "Maximal time in seconds to wait for a free slot of -max-sessions")
+	var lock_dir = flag.String("lock-dir", DefaultLockDir(), "Directory for the lock files of -max-sessions")
+	var auth = flag.String("auth", AUTH_SESSION, "Authentication method: session or basic")
+	var verbosity int
+	flag.Var(&VerbosityFlag{Verbosity: &verbosity, Level: VERBOSE_REQUESTS}, "v", "Log requested URLs, status codes and timings to standard error")
+	flag.Var(&VerbosityFlag{Verbosity: &verbosity, Level: VERBOSE_HEADERS}, "vv", "Log requests including headers to standard error")
+	flag.Var(&VerbosityFlag{Verbosity: &verbosity, Level: VERBOSE_BODIES}, "vvv", "Log requests including headers and bodies to standard error")
 	var help = flag.Bool("help", false, "Show help")
 	var status NagiosState
+	var rules *ThresholdRules
+
+	RegisterCheckFlags()
 
 	flag.Usage = ShowUsage
-	flag.Parse()
+
+	args, err := ExpandExtraOpts(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: Can't read --extra-opts: %s\n", err.Error())
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	flag.CommandLine.Parse(args)
 
 	if *help {
 		ShowUsage()
 		os.Exit(0)
 	}
 
-	if *host == "" {
+	if *config_file != "" {
+		*host, err = ApplyConfigFile(*config_file, *host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Can't read configuration file: %s\n", err.Error())
+			os.Exit(NAGIOS_UNKNOWN)
+		}
+	}
+
+	if *record != "" && *replay != "" {
+		fmt.Fprintf(os.Stderr, "ERROR: -record and -replay can't be used together\n")
+		ShowUsage()
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	if *replay != "" && *listen != "" {
+		fmt.Fprintf(os.Stderr, "ERROR: -replay can't be used in exporter mode\n")
+		ShowUsage()
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	if *response_cache != "" && (*record != "" || *replay != "") {
+		fmt.Fprintf(os.Stderr, "ERROR: -response-cache can't be used together with -record or -replay\n")
+		ShowUsage()
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	// replayed responses don't require a connection to the management board
+	if *replay != "" && *host == "" {
+		*host = *replay
+	}
+
+	if *host == "" && *listen == "" {
 		fmt.Fprintf(os.Stderr, "ERROR: No hostname specified\n")
 		ShowUsage()
 		os.Exit(NAGIOS_UNKNOWN)
 	}
 
-	if *user == "" {
+	if *user == "" && *user_env == "" && *user_command == "" && *replay == "" {
 		fmt.Fprintf(os.Stderr, "ERROR: No username specified\n")
 		ShowUsage()
 		os.Exit(NAGIOS_UNKNOWN)
 	}
 
-	if *password == "" && *password_file == "" {
-		fmt.Fprintf(os.Stderr, "ERROR: No password or password file specified\n")
+	if *password == "" && *password_file == "" && *password_env == "" && *password_command == "" && *replay == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: No password, password file, password environment variable or password command specified\n")
 		ShowUsage()
 		os.Exit(NAGIOS_UNKNOWN)
 	}
@@ -61,98 +124,251 @@ func main() {
 		os.Exit(NAGIOS_UNKNOWN)
 	}
 
-	if *password_file != "" {
-		passwd, err := ReadSingleLine(*password_file)
+	if *output != "short" && *output != "long" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid output format %s\n", *output)
+		ShowUsage()
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	if (*client_cert == "") != (*client_key == "") {
+		fmt.Fprintf(os.Stderr, "ERROR: -client-cert and -client-key must be used together\n")
+		ShowUsage()
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	if *fingerprint != "" {
+		_, err = NormaliseFingerprint(*fingerprint)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: %s", err.Error())
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
 			os.Exit(NAGIOS_UNKNOWN)
 		}
-		password = &passwd
 	}
 
-	rf := redfish.Redfish{
-		Hostname:    *host,
-		Port:        *port,
-		Username:    *user,
-		Password:    *password,
-		InsecureSSL: *insecure_ssl,
-		Timeout:     time.Duration(*timeout) * time.Second,
+	if *proxy != "" {
+		proxy_url, err := url.Parse(*proxy)
+		if err != nil || proxy_url.Host == "" || (proxy_url.Scheme != "http" && proxy_url.Scheme != "https" && proxy_url.Scheme != "socks5") {
+			fmt.Fprintf(os.Stderr, "ERROR: Invalid proxy URL %s, expected http://<host>:<port> or socks5://<host>:<port>\n", *proxy)
+			os.Exit(NAGIOS_UNKNOWN)
+		}
 	}
 
-	// setup session
-	err := rf.Initialise()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Initialisation failed for %s: %s\n", rf.Hostname, err.Error()))
+	if *auth != AUTH_SESSION && *auth != AUTH_BASIC {
+		fmt.Fprintf(os.Stderr, "ERROR: Invalid authentication method %s\n", *auth)
+		ShowUsage()
 		os.Exit(NAGIOS_UNKNOWN)
 	}
 
-	err = rf.Login()
-	if err != nil {
-		fmt.Fprint(os.Stderr, fmt.Sprintf("ERROR: Login on %s failed for user %s: %s\n", rf.Hostname, rf.Username, err.Error()))
-		os.Exit(NAGIOS_UNKNOWN)
+	checks := RequestedChecks()
+	for _, c := range checks {
+		err = c.Validate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+			ShowUsage()
+			os.Exit(NAGIOS_UNKNOWN)
+		}
 	}
 
-	defer rf.Logout()
+	start := time.Now()
 
-	if *check_installed_memory != "" {
-		m, err := strconv.Atoi(*check_installed_memory)
+	// the exporter runs until it is stopped
+	if *listen == "" {
+		StartDeadline(time.Duration(*deadline)*time.Second, func(msg string) {
+			rc, msg := FormatStatus(*output, unknownState(errors.New(msg)))
+			fmt.Println(msg)
+			os.Exit(rc)
+		})
+	}
+
+	if *rules_file != "" {
+		rules, err = ReadThresholdRules(*rules_file)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Can't convert %s to a number: %s\n", *check_installed_memory, err.Error()))
-			os.Exit(NAGIOS_UNKNOWN)
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Can't read rules from %s: %s", *rules_file, err.Error())))
 		}
+	}
 
-		status, _ = CheckInstalledMemory(rf, *system_id, m)
-	} else if *check_installed_cpus != "" {
-		c, err := strconv.Atoi(*check_installed_cpus)
+	SetPhase("reading credentials")
+	if *password_file != "" {
+		passwd, err := ReadSingleLine(*password_file)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Can't convert %s to a number: %s\n", *check_installed_memory, err.Error()))
-			os.Exit(NAGIOS_UNKNOWN)
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Can't read password from -password-file: %s", err.Error())))
 		}
-		status, _ = CheckInstalledCpus(rf, *system_id, c)
-	} else if *check_thermal {
-		status, _ = CheckThermal(rf, *chassis_id)
-	} else if *check_fans {
-		status, _ = CheckFans(rf, *chassis_id)
-	} else if *check_voltages {
-		status, _ = CheckVoltages(rf, *chassis_id)
-	} else if *check_psu != "" {
-		splitted := strings.Split(*check_psu, ",")
-		if len(splitted) != 2 {
-			fmt.Fprintf(os.Stderr, "ERROR: Invalid format for -check-psu\n")
-			ShowUsage()
-			os.Exit(NAGIOS_UNKNOWN)
+		password = &passwd
+	}
+
+	if *user_env != "" {
+		username, err := ReadSecretFromEnv(*user_env)
+		if err != nil {
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Can't read username: %s", err.Error())))
 		}
+		user = &username
+	}
 
-		w, err := strconv.Atoi(splitted[0])
+	if *user_command != "" {
+		username, err := ReadSecretFromCommand(*user_command, time.Duration(*secret_timeout)*time.Second)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Can't convert %s to a number: %s\n", splitted[0], err.Error()))
-			os.Exit(NAGIOS_UNKNOWN)
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Can't read username from -user-command: %s", err.Error())))
 		}
-		c, err := strconv.Atoi(splitted[1])
+		user = &username
+	}
+
+	if *password_env != "" {
+		passwd, err := ReadSecretFromEnv(*password_env)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Can't convert %s to a number: %s\n", splitted[1], err.Error()))
-			os.Exit(NAGIOS_UNKNOWN)
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Can't read password: %s", err.Error())))
 		}
-		if w <= 0 || c <= 0 {
-			fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Warning and critical threshold must be greater than null"))
-			os.Exit(NAGIOS_UNKNOWN)
+		password = &passwd
+	}
+
+	if *password_command != "" {
+		passwd, err := ReadSecretFromCommand(*password_command, time.Duration(*secret_timeout)*time.Second)
+		if err != nil {
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Can't read password from -password-command: %s", err.Error())))
 		}
+		password = &passwd
+	}
 
-		if c > w {
-			fmt.Fprintf(os.Stderr, fmt.Sprintf("ERROR: Critical threshold must be greater or equal than warning threshold\n"))
-			os.Exit(NAGIOS_UNKNOWN)
+	rf := BmcClient{
+		Hostname: *host,
+		Port:     *port,
+		Username: *user,
+		Password: *password,
+		Tls: TlsOptions{
+			InsecureSSL: *insecure_ssl,
+			CaFile:      *ca_file,
+			ClientCert:  *client_cert,
+			ClientKey:   *client_key,
+			Fingerprint: *fingerprint,
+		},
+		Timeout:    time.Duration(*timeout) * time.Second,
+		Proxy:      *proxy,
+		Verbosity:  verbosity,
+		Record:     *record,
+		Replay:     *replay,
+		Auth:       *auth,
+		Retries:    *retries,
+		RetryDelay: time.Duration(*retry_delay) * time.Second,
+	}
+
+	if *response_cache != "" {
+		rf.ResponseCache = &ResponseCache{
+			Directory: *response_cache,
+			Ttl:       time.Duration(*response_cache_ttl) * time.Second,
+			LockWait:  rf.Timeout,
+		}
+	}
+
+	if *listen != "" {
+		err = RunExporter(*listen, rf)
+		fmt.Fprintf(os.Stderr, "ERROR: Exporter on %s failed: %s\n", *listen, err.Error())
+		os.Exit(NAGIOS_UNKNOWN)
+	}
+
+	// the deadline applies to a single plugin run, the exporter runs until it is stopped
+	if *deadline > 0 {
+		rf.Deadline = start.Add(time.Duration(*deadline) * time.Second)
+	}
+
+	var slot *FileLock
+	acquire_slot := func() error {
+		var err error
+
+		if *max_sessions == 0 {
+			return nil
+		}
+
+		// waiting counts towards the overall deadline
+		wait := time.Duration(*max_sessions_wait) * time.Second
+		if !rf.Deadline.IsZero() && time.Until(rf.Deadline) < wait {
+			wait = time.Until(rf.Deadline)
 		}
 
-		status, _ = CheckPsu(rf, *chassis_id, w, c)
-	} else if *check_general {
-		status, _ = CheckGeneralHealth(rf, *system_id)
+		slot, err = AcquireSessionSlot(*lock_dir, rf.Hostname, rf.Port, *max_sessions, wait)
+		return err
+	}
+
+	var keep_session bool
+	login := func() error {
+		var err error
+
+		// without sessions there is nothing to cache
+		if *session_cache != "" && rf.Auth == AUTH_SESSION && rf.Replay == "" {
+			keep_session, err = LoginWithSessionCache(&rf, *session_cache)
+		} else {
+			err = rf.Login()
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
-			os.Exit(NAGIOS_UNKNOWN)
+			return err
+		}
+
+		// cached sessions will be reused by the next run
+		if !keep_session {
+			// the cleanup runs concurrently to the checks, it must not modify the client used by them
+			session := rf
+			SetCleanup(func() { session.Logout() })
 		}
+		return nil
 	}
 
-	rc, msg := ProcessStatus(status)
+	if rf.ResponseCache != nil {
+		// no session is required as long as all responses are found in the cache
+		rf.OnDemandLogin = func() error {
+			err := acquire_slot()
+			if err == nil {
+				err = login()
+			}
+			if err != nil {
+				return errors.New(fmt.Sprintf("Login on %s failed for user %s: %s", rf.Hostname, rf.Username, err.Error()))
+			}
+			return nil
+		}
+	}
+
+	// setup session
+	SetPhase("initialisation")
+	err = rf.Initialise()
+	if _, is_proxy_err := err.(ProxyError); is_proxy_err {
+		ExitUnknown(*output, errors.New(fmt.Sprintf("Can't connect to %s: %s", rf.Hostname, err.Error())))
+	}
+	if err != nil {
+		ExitUnknown(*output, errors.New(fmt.Sprintf("Initialisation failed for %s: %s", rf.Hostname, err.Error())))
+	}
+
+	if rf.OnDemandLogin == nil {
+		SetPhase("waiting for session slot")
+		err = acquire_slot()
+		if err != nil {
+			ExitUnknown(*output, err)
+		}
+
+		SetPhase("login")
+		err = login()
+		if err != nil {
+			ExitUnknown(*output, errors.New(fmt.Sprintf("Login on %s failed for user %s: %s", rf.Hostname, rf.Username, err.Error())))
+		}
+	}
+
+	// checks requesting the same resources share the responses
+	status = RunChecks(NewCachedClient(&rf), checks, CheckOptions{
+		ChassisId: *chassis_id,
+		SystemId:  *system_id,
+		Rules:     rules,
+	})
+
+	status.Retries = rf.RetryCount()
+
+	// os.Exit doesn't run deferred functions, so the session must be closed here
+	SetPhase("logout")
+	SetCleanup(nil)
+	if !keep_session {
+		rf.Logout()
+	}
+	if slot != nil {
+		slot.Unlock()
+	}
+
+	FinishDeadline()
+
+	rc, msg := FormatStatus(*output, status)
 	fmt.Println(msg)
 
 	os.Exit(rc)
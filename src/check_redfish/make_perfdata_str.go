@@ -87,3 +87,61 @@ func MakePerfDataString(label string, val string, uom *string, warn *string, cri
 	perfdata = strings.TrimRight(perfdata, ";")
 	return perfdata, nil
 }
+
+// PrefixPerfDataLabel adds prefix to the label of a performance data string created by MakePerfDataString
+func PrefixPerfDataLabel(perfdata string, prefix string) string {
+	if strings.HasPrefix(perfdata, "'") {
+		return "'" + prefix + perfdata[1:]
+	}
+	return prefix + perfdata
+}
+
+// PerfData - performance data, parsed from a string created by MakePerfDataString
+type PerfData struct {
+	Label    string `json:"label"`
+	Value    string `json:"value"`
+	UOM      string `json:"uom,omitempty"`
+	Warning  string `json:"warning,omitempty"`
+	Critical string `json:"critical,omitempty"`
+	Min      string `json:"min,omitempty"`
+	Max      string `json:"max,omitempty"`
+}
+
+// ParsePerfDataString splits a performance data string created by MakePerfDataString into its fields
+func ParsePerfDataString(perfdata string) (PerfData, error) {
+	var result PerfData
+
+	eq := strings.LastIndex(perfdata, "=")
+	if eq <= 0 {
+		return result, errors.New(fmt.Sprintf("Invalid performance data %s", perfdata))
+	}
+
+	result.Label = strings.Trim(perfdata[:eq], "'")
+	fields := strings.Split(perfdata[eq+1:], ";")
+
+	// split value and unit of measurement
+	val := fields[0]
+	uom_start := strings.IndexFunc(val, func(c rune) bool {
+		return !strings.ContainsRune("-0123456789.U", c)
+	})
+	if uom_start >= 0 {
+		result.UOM = val[uom_start:]
+		val = val[:uom_start]
+	}
+	result.Value = val
+
+	if len(fields) > 1 {
+		result.Warning = fields[1]
+	}
+	if len(fields) > 2 {
+		result.Critical = fields[2]
+	}
+	if len(fields) > 3 {
+		result.Min = fields[3]
+	}
+	if len(fields) > 4 {
+		result.Max = fields[4]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+// MergeNagiosState appends messages and performance data of state b to state a
+func MergeNagiosState(a NagiosState, b NagiosState) NagiosState {
+	a.Critical = append(a.Critical, b.Critical...)
+	a.Warning = append(a.Warning, b.Warning...)
+	a.Ok = append(a.Ok, b.Ok...)
+	a.Unknown = append(a.Unknown, b.Unknown...)
+	a.PerfData = append(a.PerfData, b.PerfData...)
+	a.Components = append(a.Components, b.Components...)
+	a.Retries += b.Retries
+	return a
+}
+
+// ReportComponent adds msg to the messages of state s and records component c with state s
+func ReportComponent(n *NagiosState, s int, msg string, c ComponentState) {
+	switch s {
+	case NAGIOS_OK:
+		n.Ok = append(n.Ok, msg)
+	case NAGIOS_WARNING:
+		n.Warning = append(n.Warning, msg)
+	case NAGIOS_CRITICAL:
+		n.Critical = append(n.Critical, msg)
+	default:
+		s = NAGIOS_UNKNOWN
+		n.Unknown = append(n.Unknown, msg)
+	}
+
+	c.State = NAGIOS_STATE_NAMES[s]
+	c.Message = msg
+	n.Components = append(n.Components, c)
+}
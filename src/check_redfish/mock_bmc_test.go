@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	MOCK_USER     = "monitoring"
+	MOCK_PASSWORD = "s3cr3t"
+)
+
+// MockProfile - Redfish documents of a management board, indexed by URI, including the quirks of the vendor
+type MockProfile struct {
+	Vendor string `json:"vendor"`
+	// report the session location as absolute URL instead of a path (HPE iLO)
+	AbsoluteSessionLocation bool                       `json:"absolute_session_location"`
+	Documents               map[string]json.RawMessage `json:"documents"`
+}
+
+// MockBmc - Redfish service serving the documents of a profile, GET requests (except for the service root)
+// require a session or HTTP basic authentication
+type MockBmc struct {
+	Server  *httptest.Server
+	Profile *MockProfile
+
+	mutex    sync.Mutex
+	sessions map[string]string
+	next_id  int
+	requests []string
+}
+
+// LoadMockProfile reads the profile name from testdata/profiles
+func LoadMockProfile(name string) (*MockProfile, error) {
+	var profile MockProfile
+
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "profiles", name+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	return &profile, nil
+}
+
+// loadPatchedProfile loads profile, patch replaces or adds (or removes if the value is empty) documents of the profile
+func loadPatchedProfile(t *testing.T, profile string, patch map[string]string) *MockProfile {
+	p, err := LoadMockProfile(profile)
+	if err != nil {
+		t.Fatalf("Can't load profile %s: %s", profile, err.Error())
+	}
+
+	for uri, doc := range patch {
+		if doc == "" {
+			delete(p.Documents, uri)
+		} else {
+			p.Documents[uri] = json.RawMessage(doc)
+		}
+	}
+
+	return p
+}
+
+// NewMockBmc starts a mock management board for profile, see loadPatchedProfile for patch
+func NewMockBmc(t *testing.T, profile string, patch map[string]string) *MockBmc {
+	m := &MockBmc{
+		Profile:  loadPatchedProfile(t, profile, patch),
+		sessions: make(map[string]string),
+	}
+	m.Server = httptest.NewTLSServer(m)
+	t.Cleanup(m.Server.Close)
+
+	return m
+}
+
+// document returns the document of uri, with or without trailing slash
+func (m *MockBmc) document(uri string) (json.RawMessage, bool) {
+	doc, found := m.Profile.Documents[uri]
+	if found {
+		return doc, true
+	}
+
+	if strings.HasSuffix(uri, "/") {
+		doc, found = m.Profile.Documents[strings.TrimSuffix(uri, "/")]
+	} else {
+		doc, found = m.Profile.Documents[uri+"/"]
+	}
+	return doc, found
+}
+
+func (m *MockBmc) sessionsUri() string {
+	var root serviceRoot
+
+	doc, _ := m.document("/redfish/v1/")
+	json.Unmarshal(doc, &root)
+	return root.Links.Sessions.Id
+}
+
+func (m *MockBmc) authenticated(r *http.Request) bool {
+	user, password, ok := r.BasicAuth()
+	if ok {
+		return user == MOCK_USER && password == MOCK_PASSWORD
+	}
+
+	_, found := m.sessions[r.Header.Get("X-Auth-Token")]
+	return found
+}
+
+func (m *MockBmc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests = append(m.requests, r.Method+" "+r.URL.Path)
+	sessions := m.sessionsUri()
+
+	// login
+	if r.Method == "POST" && strings.TrimSuffix(r.URL.Path, "/") == strings.TrimSuffix(sessions, "/") {
+		var credentials struct {
+			UserName string
+			Password string
+		}
+
+		err := json.NewDecoder(r.Body).Decode(&credentials)
+		if err != nil || credentials.UserName != MOCK_USER || credentials.Password != MOCK_PASSWORD {
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
+		m.next_id++
+		token := fmt.Sprintf("token-%d", m.next_id)
+		location := strings.TrimSuffix(sessions, "/") + "/" + strconv.Itoa(m.next_id)
+		m.sessions[token] = location
+
+		if m.Profile.AbsoluteSessionLocation {
+			location = m.Server.URL + location + "/"
+		}
+		w.Header().Set("X-Auth-Token", token)
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	is_root := r.URL.Path == "/redfish/v1/" || r.URL.Path == "/redfish/v1"
+	if !is_root && !m.authenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// logout
+	if r.Method == "DELETE" {
+		for token, location := range m.sessions {
+			if location == strings.TrimSuffix(r.URL.Path, "/") {
+				delete(m.sessions, token)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	doc, found := m.document(r.URL.Path)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
+
+// Sessions returns the number of open sessions
+func (m *MockBmc) Sessions() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.sessions)
+}
+
+// Requests returns the requests ("<method> <path>") received so far
+func (m *MockBmc) Requests() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]string{}, m.requests...)
+}
+
+// NewClient returns an uninitialised client for the mock management board
+func (m *MockBmc) NewClient() *BmcClient {
+	u, _ := url.Parse(m.Server.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	return &BmcClient{
+		Hostname: u.Hostname(),
+		Port:     port,
+		Username: MOCK_USER,
+		Password: MOCK_PASSWORD,
+		Tls:      TlsOptions{InsecureSSL: true},
+		Timeout:  5 * time.Second,
+	}
+}
+
+// Login returns a client with a session on the mock management board, the session is closed at the end of the test
+func (m *MockBmc) Login(t *testing.T) *BmcClient {
+	rf := m.NewClient()
+
+	err := rf.Initialise()
+	if err != nil {
+		t.Fatalf("Initialisation failed: %s", err.Error())
+	}
+
+	err = rf.Login()
+	if err != nil {
+		t.Fatalf("Login failed: %s", err.Error())
+	}
+	t.Cleanup(func() { rf.Logout() })
+
+	return rf
+}
+
+// checkTestCase - expected result of a check against a profile
+type checkTestCase struct {
+	name    string
+	profile string
+	// chassis or system ID
+	member string
+	// documents replaced in the profile, see loadPatchedProfile
+	patch map[string]string
+	rc    int
+	// messages which must be reported
+	messages []string
+	// expected number of performance data values, -1 to skip the test
+	perfdata int
+	err      bool
+}
+
+// runCheckTests runs check for each test case against the mock management board and against the fixture client
+func runCheckTests(t *testing.T, cases []checkTestCase, check func(rf RedfishClient, member string) (NagiosState, error)) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Run("http", func(t *testing.T) {
+				verifyCheckResult(t, tc, NewMockBmc(t, tc.profile, tc.patch).Login(t), check)
+			})
+			t.Run("fixture", func(t *testing.T) {
+				verifyCheckResult(t, tc, NewFixtureClient(t, tc.profile, tc.patch), check)
+			})
+		})
+	}
+}
+
+func verifyCheckResult(t *testing.T, tc checkTestCase, rf RedfishClient, check func(rf RedfishClient, member string) (NagiosState, error)) {
+	state, err := check(rf, tc.member)
+	if tc.err && err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+	if !tc.err && err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+
+	rc := NagiosStateRc(state)
+	if rc != tc.rc {
+		t.Errorf("Expected state %s but got %s: %+v", NAGIOS_STATE_NAMES[tc.rc], NAGIOS_STATE_NAMES[rc], state)
+	}
+
+	all := append(append(append(append([]string{}, state.Critical...), state.Warning...), state.Ok...), state.Unknown...)
+	for _, msg := range tc.messages {
+		if !containsString(all, msg) {
+			t.Errorf("Message %q not reported, got %q", msg, all)
+		}
+	}
+
+	if tc.perfdata >= 0 && len(state.PerfData) != tc.perfdata {
+		t.Errorf("Expected %d performance data values but got %d: %q", tc.perfdata, len(state.PerfData), state.PerfData)
+	}
+}
+
+func TestMockBmcProfiles(t *testing.T) {
+	for _, profile := range []string{"hpe_ilo", "dell_idrac", "lenovo_xcc", "supermicro"} {
+		t.Run(profile, func(t *testing.T) {
+			m := NewMockBmc(t, profile, nil)
+			rf := m.Login(t)
+
+			if m.Sessions() != 1 {
+				t.Errorf("Expected 1 session but got %d", m.Sessions())
+			}
+
+			_, err := rf.MapChassisById()
+			if err != nil {
+				t.Errorf("Can't read chassis: %s", err.Error())
+			}
+
+			_, err = rf.MapSystemsById()
+			if err != nil {
+				t.Errorf("Can't read systems: %s", err.Error())
+			}
+
+			err = rf.Logout()
+			if err != nil {
+				t.Errorf("Logout failed: %s", err.Error())
+			}
+
+			if m.Sessions() != 0 {
+				t.Errorf("Session has not been closed")
+			}
+		})
+	}
+}
+
+func TestMockBmcBasicAuth(t *testing.T) {
+	m := NewMockBmc(t, "dell_idrac", nil)
+	rf := m.NewClient()
+	rf.Auth = AUTH_BASIC
+
+	err := rf.Initialise()
+	if err == nil {
+		err = rf.Login()
+	}
+	if err != nil {
+		t.Fatalf("Login failed: %s", err.Error())
+	}
+
+	_, err = rf.GetSystems()
+	if err != nil {
+		t.Errorf("Request using basic authentication failed: %s", err.Error())
+	}
+
+	if m.Sessions() != 0 {
+		t.Errorf("Basic authentication must not create a session")
+	}
+}
+
+func TestMockBmcInvalidCredentials(t *testing.T) {
+	m := NewMockBmc(t, "supermicro", nil)
+	rf := m.NewClient()
+	rf.Password = "wrong"
+
+	err := rf.Initialise()
+	if err != nil {
+		t.Fatalf("Initialisation failed: %s", err.Error())
+	}
+
+	err = rf.Login()
+	e, ok := err.(HttpStatusError)
+	if !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected HTTP status 401 but got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maximal time to wait for the cleanup (e.g. logout) after the deadline expired or a signal was received
+const CLEANUP_TIMEOUT = 5 * time.Second
+
+// PluginDeadline - overall deadline of a plugin run, reports the phase the run was in when the deadline expired
+type PluginDeadline struct {
+	mutex   sync.Mutex
+	phase   string
+	cleanup func()
+	// set by the first of the deadline (or a signal) and FinishDeadline, only this one may report a result
+	expired  bool
+	finished bool
+}
+
+var plugin_deadline PluginDeadline
+
+// SetPhase sets the phase reported if the deadline expires, e.g. "login" or "check thermal"
+func SetPhase(phase string) {
+	plugin_deadline.mutex.Lock()
+	defer plugin_deadline.mutex.Unlock()
+
+	plugin_deadline.phase = phase
+}
+
+// SetCleanup sets the function to run (e.g. logout) if the deadline expires or the run is interrupted by a signal,
+// nil removes the cleanup function
+func SetCleanup(cleanup func()) {
+	plugin_deadline.mutex.Lock()
+	defer plugin_deadline.mutex.Unlock()
+
+	plugin_deadline.cleanup = cleanup
+}
+
+// StartDeadline starts the deadline of timeout (0 disables the deadline) and handles SIGTERM and SIGINT.
+// If the deadline expires report is called with the message "timed out after N seconds during <phase>"
+// after running the cleanup function, for a signal the message is "interrupted by signal <signal> during <phase>".
+// report must exit the program.
+func StartDeadline(timeout time.Duration, report func(string)) {
+	var expired <-chan time.Time
+
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		select {
+		case <-expired:
+			phase, ok := expireDeadline()
+			if !ok {
+				return
+			}
+
+			runCleanup()
+			report(fmt.Sprintf("timed out after %d seconds during %s", int(timeout.Seconds()), phase))
+
+		case sig := <-signals:
+			phase, ok := expireDeadline()
+			if !ok {
+				return
+			}
+
+			runCleanup()
+			report(fmt.Sprintf("interrupted by signal %s during %s", sig.String(), phase))
+		}
+	}()
+}
+
+// expireDeadline marks the deadline as expired and returns the current phase, false if the result is
+// already being reported by FinishDeadline
+func expireDeadline() (string, bool) {
+	plugin_deadline.mutex.Lock()
+	defer plugin_deadline.mutex.Unlock()
+
+	if plugin_deadline.finished {
+		return "", false
+	}
+	plugin_deadline.expired = true
+	return plugin_deadline.phase, true
+}
+
+// FinishDeadline stops the deadline before the result is reported, so only one result is printed. If the deadline
+// already expired it doesn't return, the deadline reports the timeout and exits.
+func FinishDeadline() {
+	plugin_deadline.mutex.Lock()
+	expired := plugin_deadline.expired
+	plugin_deadline.finished = !expired
+	plugin_deadline.mutex.Unlock()
+
+	if expired {
+		select {}
+	}
+}
+
+// runCleanup runs the cleanup function once, but doesn't wait longer than CLEANUP_TIMEOUT for it
+func runCleanup() {
+	plugin_deadline.mutex.Lock()
+	cleanup := plugin_deadline.cleanup
+	plugin_deadline.cleanup = nil
+	plugin_deadline.mutex.Unlock()
+
+	if cleanup == nil {
+		return
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		cleanup()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(CLEANUP_TIMEOUT):
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// resetDeadline resets the state left by earlier tests
+func resetDeadline() {
+	plugin_deadline.mutex.Lock()
+	defer plugin_deadline.mutex.Unlock()
+
+	plugin_deadline.phase = ""
+	plugin_deadline.cleanup = nil
+	plugin_deadline.expired = false
+	plugin_deadline.finished = false
+}
+
+func TestDeadlineExpired(t *testing.T) {
+	resetDeadline()
+	reported := make(chan string, 1)
+	cleaned := make(chan bool, 1)
+
+	SetPhase("check thermal")
+	SetCleanup(func() { cleaned <- true })
+	StartDeadline(50*time.Millisecond, func(msg string) {
+		reported <- msg
+		// report must not return
+		select {}
+	})
+
+	select {
+	case msg := <-reported:
+		if msg != "timed out after 0 seconds during check thermal" {
+			t.Errorf("Unexpected message %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Deadline has not been reported")
+	}
+
+	if len(cleaned) != 1 {
+		t.Errorf("Cleanup has not been run")
+	}
+
+	// the result of the run must not be reported after the timeout
+	finished := make(chan bool, 1)
+	go func() {
+		FinishDeadline()
+		finished <- true
+	}()
+
+	select {
+	case <-finished:
+		t.Errorf("FinishDeadline returned after the deadline expired")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDeadlineFinished(t *testing.T) {
+	resetDeadline()
+	reported := make(chan string, 1)
+
+	StartDeadline(50*time.Millisecond, func(msg string) {
+		reported <- msg
+		select {}
+	})
+	FinishDeadline()
+
+	select {
+	case msg := <-reported:
+		t.Errorf("Deadline reported %q after the run finished", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestDeadlineSignal(t *testing.T) {
+	resetDeadline()
+	reported := make(chan string, 1)
+	cleaned := make(chan bool, 1)
+
+	SetPhase("login")
+	SetCleanup(func() { cleaned <- true })
+	StartDeadline(0, func(msg string) {
+		reported <- msg
+		select {}
+	})
+
+	err := syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-reported:
+		if msg != "interrupted by signal terminated during login" {
+			t.Errorf("Unexpected message %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Signal has not been reported")
+	}
+
+	if len(cleaned) != 1 {
+		t.Errorf("Cleanup has not been run")
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"strings"
 )
 
@@ -75,3 +77,87 @@ func ProcessStatus(n NagiosState) (int, string) {
 
 	return rc, msg
 }
+
+// NagiosStateRc returns the exit code for the worst state reported
+func NagiosStateRc(n NagiosState) int {
+	if len(n.Unknown) > 0 {
+		return NAGIOS_UNKNOWN
+	} else if len(n.Critical) > 0 {
+		return NAGIOS_CRITICAL
+	} else if len(n.Warning) > 0 {
+		return NAGIOS_WARNING
+	} else if len(n.Ok) > 0 {
+		return NAGIOS_OK
+	}
+	// shouldn't happen
+	return NAGIOS_UNKNOWN
+}
+
+// ProcessStatusLong reports a summary in the first line followed by one line per result, grouped by severity,
+// and performance data at the end (see https://nagios-plugins.org/doc/guidelines.html#AEN33)
+func ProcessStatusLong(n NagiosState) (int, string) {
+	var summary = make([]string, 0)
+	var lines = make([]string, 0)
+
+	rc := NagiosStateRc(n)
+
+	if len(n.Unknown) > 0 {
+		summary = append(summary, fmt.Sprintf("%d unknown", len(n.Unknown)))
+	}
+	if len(n.Critical) > 0 {
+		summary = append(summary, fmt.Sprintf("%d critical", len(n.Critical)))
+	}
+	if len(n.Warning) > 0 {
+		summary = append(summary, fmt.Sprintf("%d warning", len(n.Warning)))
+	}
+	if len(n.Ok) > 0 {
+		summary = append(summary, fmt.Sprintf("%d ok", len(n.Ok)))
+	}
+
+	if len(summary) == 0 {
+		return rc, "No results at all found"
+	}
+	if n.Retries > 0 {
+		summary = append(summary, fmt.Sprintf("%d retried requests", n.Retries))
+	}
+	lines = append(lines, strings.Join(summary, ", "))
+
+	for _, m := range n.Unknown {
+		lines = append(lines, "[UNKNOWN] "+m)
+	}
+	for _, m := range n.Critical {
+		lines = append(lines, "[CRITICAL] "+m)
+	}
+	for _, m := range n.Warning {
+		lines = append(lines, "[WARNING] "+m)
+	}
+	for _, m := range n.Ok {
+		lines = append(lines, "[OK] "+m)
+	}
+
+	if len(n.PerfData) > 0 {
+		lines = append(lines, "| "+strings.Join(n.PerfData, " "))
+	}
+
+	return rc, strings.Join(lines, "\n")
+}
+
+// FormatStatus returns the return code and the output of n in format short, long or json
+func FormatStatus(format string, n NagiosState) (int, string) {
+	if format == "long" {
+		return ProcessStatusLong(n)
+	} else if format == "json" {
+		return ProcessStatusJson(n)
+	}
+	return ProcessStatus(n)
+}
+
+// ExitUnknown reports err as UNKNOWN in the output format and exits, so tools parsing the output
+// (e.g. -output=json) get a valid result for failures before or instead of the checks as well
+func ExitUnknown(format string, err error) {
+	FinishDeadline()
+
+	rc, msg := FormatStatus(format, unknownState(err))
+	fmt.Println(msg)
+	os.Exit(rc)
+}
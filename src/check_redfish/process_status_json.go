@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JsonResult - result of all checks, reported by -output=json
+type JsonResult struct {
+	State      string           `json:"state"`
+	ExitCode   int              `json:"exit_code"`
+	Critical   []string         `json:"critical"`
+	Warning    []string         `json:"warning"`
+	Ok         []string         `json:"ok"`
+	Unknown    []string         `json:"unknown"`
+	Components []ComponentState `json:"components"`
+	PerfData   []PerfData       `json:"perfdata"`
+	Retries    int              `json:"retries"`
+}
+
+// ProcessStatusJson reports the results as JSON document
+func ProcessStatusJson(n NagiosState) (int, string) {
+	rc := NagiosStateRc(n)
+
+	result := JsonResult{
+		State:      NAGIOS_STATE_NAMES[rc],
+		ExitCode:   rc,
+		Critical:   make([]string, 0),
+		Warning:    make([]string, 0),
+		Ok:         make([]string, 0),
+		Unknown:    make([]string, 0),
+		Components: make([]ComponentState, 0),
+		PerfData:   make([]PerfData, 0),
+		Retries:    n.Retries,
+	}
+
+	result.Critical = append(result.Critical, n.Critical...)
+	result.Warning = append(result.Warning, n.Warning...)
+	result.Ok = append(result.Ok, n.Ok...)
+	result.Unknown = append(result.Unknown, n.Unknown...)
+	result.Components = append(result.Components, n.Components...)
+
+	for _, p := range n.PerfData {
+		pd, err := ParsePerfDataString(p)
+		if err == nil {
+			result.PerfData = append(result.PerfData, pd)
+		}
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return NAGIOS_UNKNOWN, fmt.Sprintf("{\"state\":\"UNKNOWN\",\"exit_code\":%d,\"unknown\":[%q]}", NAGIOS_UNKNOWN, err.Error())
+	}
+
+	return rc, string(raw)
+}
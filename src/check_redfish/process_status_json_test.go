@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestProcessStatusJson(t *testing.T) {
+	reading := 40.0
+
+	rc, msg := ProcessStatusJson(NagiosState{
+		Warning: []string{"Sensor \"Inlet Temp\" reports 40°C, warning range is ~:35"},
+		Ok:      []string{"Fan 1 is reported as ok"},
+		Components: []ComponentState{
+			{
+				Name:     "Inlet Temp",
+				Type:     "thermal",
+				State:    "WARNING",
+				Health:   "Warning",
+				Reading:  &reading,
+				Unit:     "Cel",
+				Warning:  "~:35",
+				Critical: "~:45",
+				Message:  "Sensor \"Inlet Temp\" reports 40°C, warning range is ~:35",
+			},
+		},
+		PerfData: []string{"'Inlet Temp'=40;~:35;~:45;;", "invalid"},
+		Retries:  2,
+	})
+	if rc != NAGIOS_WARNING {
+		t.Errorf("Expected return code %d but got %d", NAGIOS_WARNING, rc)
+	}
+
+	expected := `{"state":"WARNING","exit_code":1,` +
+		`"critical":[],` +
+		`"warning":["Sensor \"Inlet Temp\" reports 40°C, warning range is ~:35"],` +
+		`"ok":["Fan 1 is reported as ok"],` +
+		`"unknown":[],` +
+		`"components":[{"name":"Inlet Temp","type":"thermal","state":"WARNING","health":"Warning","reading":40,"unit":"Cel",` +
+		`"warning":"~:35","critical":"~:45","message":"Sensor \"Inlet Temp\" reports 40°C, warning range is ~:35"}],` +
+		`"perfdata":[{"label":"Inlet Temp","value":"40","warning":"~:35","critical":"~:45"}],` +
+		`"retries":2}`
+	if msg != expected {
+		t.Errorf("Expected\n%s\nbut got\n%s", expected, msg)
+	}
+}
+
+func TestProcessStatusJsonUnknown(t *testing.T) {
+	var result JsonResult
+
+	// failures before the checks, e.g. invalid credentials, are reported with the same schema
+	rc, msg := FormatStatus("json", unknownState(errors.New("Login failed")))
+	if rc != NAGIOS_UNKNOWN {
+		t.Errorf("Expected return code %d but got %d", NAGIOS_UNKNOWN, rc)
+	}
+
+	err := json.Unmarshal([]byte(msg), &result)
+	if err != nil {
+		t.Fatalf("Invalid JSON %s: %s", msg, err.Error())
+	}
+
+	if result.State != "UNKNOWN" || result.ExitCode != NAGIOS_UNKNOWN {
+		t.Errorf("Unexpected state %s and exit code %d", result.State, result.ExitCode)
+	}
+	if len(result.Unknown) != 1 || result.Unknown[0] != "Login failed" {
+		t.Errorf("Unexpected unknown messages %q", result.Unknown)
+	}
+	if result.Critical == nil || result.Warning == nil || result.Ok == nil || result.Components == nil || result.PerfData == nil {
+		t.Errorf("Lists must be reported as empty arrays instead of null: %s", msg)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProcessStatusLong(t *testing.T) {
+	var ok = make([]string, 0)
+
+	for i := 1; i <= 37; i++ {
+		ok = append(ok, fmt.Sprintf("Sensor %d is reported as ok", i))
+	}
+
+	for _, tc := range []struct {
+		name  string
+		state NagiosState
+		rc    int
+		lines []string
+	}{
+		{
+			name: "summary and grouping by severity",
+			state: NagiosState{
+				Critical: []string{"Fan 1 failed", "PSU 2 failed"},
+				Warning:  []string{"Inlet Temp reports 40°C"},
+				Ok:       ok,
+				PerfData: []string{"'Inlet Temp'=40;35;45", "'Fan 1'=0RPM"},
+			},
+			rc: NAGIOS_CRITICAL,
+			lines: append(append([]string{
+				"2 critical, 1 warning, 37 ok",
+				"[CRITICAL] Fan 1 failed",
+				"[CRITICAL] PSU 2 failed",
+				"[WARNING] Inlet Temp reports 40°C",
+			}, prefixLines("[OK] ", ok)...), "| 'Inlet Temp'=40;35;45 'Fan 1'=0RPM"),
+		},
+		{
+			name: "unknown first, retries",
+			state: NagiosState{
+				Unknown: []string{"Chassis not found"},
+				Ok:      []string{"Fan 1 is reported as ok"},
+				Warning: []string{"Fan 2 is reported as warning"},
+				Retries: 3,
+			},
+			rc: NAGIOS_UNKNOWN,
+			lines: []string{
+				"1 unknown, 1 warning, 1 ok, 3 retried requests",
+				"[UNKNOWN] Chassis not found",
+				"[WARNING] Fan 2 is reported as warning",
+				"[OK] Fan 1 is reported as ok",
+			},
+		},
+		{
+			name:  "ok without performance data",
+			state: NagiosState{Ok: []string{"Fan 1 is reported as ok"}},
+			rc:    NAGIOS_OK,
+			lines: []string{"1 ok", "[OK] Fan 1 is reported as ok"},
+		},
+		{
+			name:  "no results",
+			state: NagiosState{Retries: 1},
+			rc:    NAGIOS_UNKNOWN,
+			lines: []string{"No results at all found"},
+		},
+	} {
+		rc, msg := ProcessStatusLong(tc.state)
+		if rc != tc.rc {
+			t.Errorf("%s: expected return code %d but got %d", tc.name, tc.rc, rc)
+		}
+
+		expected := strings.Join(tc.lines, "\n")
+		if msg != expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", tc.name, expected, msg)
+		}
+	}
+}
+
+func prefixLines(prefix string, lines []string) []string {
+	var result = make([]string, 0)
+	for _, l := range lines {
+		result = append(result, prefix+l)
+	}
+	return result
+}
+
+func TestFormatStatus(t *testing.T) {
+	var state = NagiosState{
+		Warning:  []string{"Fan 2 is reported as warning"},
+		Ok:       []string{"Fan 1 is reported as ok"},
+		PerfData: []string{"'Fan 1'=4000RPM"},
+	}
+
+	for format, expected := range map[string]string{
+		"short": "Fan 2 is reported as warning; Fan 1 is reported as ok | 'Fan 1'=4000RPM",
+		"long":  "1 warning, 1 ok\n[WARNING] Fan 2 is reported as warning\n[OK] Fan 1 is reported as ok\n| 'Fan 1'=4000RPM",
+	} {
+		rc, msg := FormatStatus(format, state)
+		if rc != NAGIOS_WARNING {
+			t.Errorf("Format %s: expected return code %d but got %d", format, NAGIOS_WARNING, rc)
+		}
+		if msg != expected {
+			t.Errorf("Format %s: expected %q but got %q", format, expected, msg)
+		}
+	}
+}
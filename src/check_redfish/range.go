@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Range - threshold range as defined in https://nagios-plugins.org/doc/guidelines.html#THRESHOLDFORMAT
+type Range struct {
+	Start float64
+	End   float64
+	// alert if value is inside of Start and End (range starts with "@")
+	Inside bool
+}
+
+// ParseRange converts a threshold range to a Range
+func ParseRange(s string) (*Range, error) {
+	var r = Range{
+		Start: 0,
+		End:   math.Inf(1),
+	}
+	var err error
+
+	/*
+	   Range definitions:
+
+	       10      < 0 or > 10, (outside the range of {0 .. 10})
+	       10:     < 10, (outside {10 .. ∞})
+	       ~:10    > 10, (outside the range of {-∞ .. 10})
+	       10:20   < 10 or > 20, (outside the range of {10 .. 20})
+	       @10:20  ≥ 10 and ≤ 20, (inside the range of {10 .. 20})
+
+	   Source: https://nagios-plugins.org/doc/guidelines.html#THRESHOLDFORMAT
+	*/
+
+	_s := strings.TrimSpace(s)
+	if _s == "" {
+		return nil, errors.New("Range can't be empty")
+	}
+
+	if strings.HasPrefix(_s, "@") {
+		r.Inside = true
+		_s = _s[1:]
+	}
+
+	splitted := strings.Split(_s, ":")
+	if len(splitted) > 2 {
+		return nil, errors.New(fmt.Sprintf("Invalid range %s", s))
+	}
+
+	if len(splitted) == 1 {
+		r.End, err = strconv.ParseFloat(splitted[0], 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid end of range %s: %s", s, err.Error()))
+		}
+	} else {
+		if splitted[0] == "~" {
+			r.Start = math.Inf(-1)
+		} else if splitted[0] != "" {
+			r.Start, err = strconv.ParseFloat(splitted[0], 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid start of range %s: %s", s, err.Error()))
+			}
+		}
+
+		if splitted[1] != "" {
+			r.End, err = strconv.ParseFloat(splitted[1], 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid end of range %s: %s", s, err.Error()))
+			}
+		}
+	}
+
+	if r.Start > r.End {
+		return nil, errors.New(fmt.Sprintf("Start of range %s is greater than the end", s))
+	}
+
+	return &r, nil
+}
+
+// Alert reports if value v should raise an alert
+func (r *Range) Alert(v float64) bool {
+	inside := v >= r.Start && v <= r.End
+	if r.Inside {
+		return inside
+	}
+	return !inside
+}
+
+// String returns the range in the threshold format, as used for the warn and crit fields of performance data.
+// An empty string is returned for a nil range.
+func (r *Range) String() string {
+	var result string
+
+	if r == nil {
+		return result
+	}
+
+	if r.Inside {
+		result = "@"
+	}
+
+	if math.IsInf(r.Start, -1) {
+		result += "~:"
+	} else if r.Start != 0 {
+		result += strconv.FormatFloat(r.Start, 'f', -1, 64) + ":"
+	}
+
+	if !math.IsInf(r.End, 1) {
+		result += strconv.FormatFloat(r.End, 'f', -1, 64)
+	} else if r.Start == 0 {
+		result += "0:"
+	}
+
+	return result
+}
+
+// ThresholdState returns NAGIOS_CRITICAL or NAGIOS_WARNING if v raises an alert for the critical or warning range, NAGIOS_OK otherwise.
+// nil ranges are ignored.
+func ThresholdState(v float64, warn *Range, crit *Range) int {
+	if crit != nil && crit.Alert(v) {
+		return NAGIOS_CRITICAL
+	}
+
+	if warn != nil && warn.Alert(v) {
+		return NAGIOS_WARNING
+	}
+
+	return NAGIOS_OK
+}
+
+// NewThresholdRange returns the range lower:upper from thresholds reported by the management board.
+// A nil threshold is considered as not set, nil is returned if neither threshold is set.
+func NewThresholdRange(lower *float64, upper *float64) *Range {
+	var r = Range{
+		Start: math.Inf(-1),
+		End:   math.Inf(1),
+	}
+
+	if lower == nil && upper == nil {
+		return nil
+	}
+
+	if lower != nil {
+		r.Start = *lower
+	}
+
+	if upper != nil {
+		r.End = *upper
+	}
+
+	return &r
+}
+
+// BoardThresholds returns warn and crit, ranges which aren't set (e.g. by the command line or the rules file)
+// are replaced by the non-critical and critical thresholds reported by the management board
+func BoardThresholds(warn *Range, crit *Range, lower_nc *float64, lower_c *float64, upper_nc *float64, upper_c *float64) (*Range, *Range) {
+	if warn == nil {
+		warn = NewThresholdRange(lower_nc, upper_nc)
+	}
+	if crit == nil {
+		crit = NewThresholdRange(lower_c, upper_c)
+	}
+	return warn, crit
+}
+
+// IntThreshold converts a threshold reported as integer, nil if the threshold is not set
+func IntThreshold(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+
+	f := float64(*v)
+	return &f
+}
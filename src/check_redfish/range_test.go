@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	for _, tc := range []struct {
+		s     string
+		r     Range
+		err   bool
+		alert []float64
+		ok    []float64
+	}{
+		{s: "10", r: Range{Start: 0, End: 10}, alert: []float64{-1, 10.5}, ok: []float64{0, 5, 10}},
+		{s: "10:", r: Range{Start: 10, End: math.Inf(1)}, alert: []float64{9.9}, ok: []float64{10, 1000}},
+		{s: "~:10", r: Range{Start: math.Inf(-1), End: 10}, alert: []float64{10.1}, ok: []float64{-1000, 10}},
+		{s: "10:20", r: Range{Start: 10, End: 20}, alert: []float64{9, 21}, ok: []float64{10, 15, 20}},
+		{s: "@10:20", r: Range{Start: 10, End: 20, Inside: true}, alert: []float64{10, 15, 20}, ok: []float64{9, 21}},
+		{s: " 10:20 ", r: Range{Start: 10, End: 20}},
+		{s: "", err: true},
+		{s: "5:1", err: true},
+		{s: "1:2:3", err: true},
+		{s: "foo", err: true},
+		{s: "foo:10", err: true},
+		{s: "10:bar", err: true},
+		{s: "@", err: true},
+	} {
+		r, err := ParseRange(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Range %q: expected an error but got %+v", tc.s, *r)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Range %q: unexpected error: %s", tc.s, err.Error())
+			continue
+		}
+		if *r != tc.r {
+			t.Errorf("Range %q: expected %+v but got %+v", tc.s, tc.r, *r)
+		}
+
+		for _, v := range tc.alert {
+			if !r.Alert(v) {
+				t.Errorf("Range %q: expected an alert for %v", tc.s, v)
+			}
+		}
+		for _, v := range tc.ok {
+			if r.Alert(v) {
+				t.Errorf("Range %q: unexpected alert for %v", tc.s, v)
+			}
+		}
+	}
+}
+
+func TestRangeString(t *testing.T) {
+	for _, s := range []string{"10", "10:", "~:10", "10:20", "@10:20", "@~:0"} {
+		r, err := ParseRange(s)
+		if err != nil {
+			t.Fatalf("Range %q: unexpected error: %s", s, err.Error())
+		}
+		if r.String() != s {
+			t.Errorf("Range %q: String() returned %q", s, r.String())
+		}
+	}
+
+	var r *Range
+	if r.String() != "" {
+		t.Errorf("Expected an empty string for a nil range but got %q", r.String())
+	}
+}
+
+func TestThresholdState(t *testing.T) {
+	warn, _ := ParseRange("10:20")
+	crit, _ := ParseRange("5:25")
+
+	for _, tc := range []struct {
+		v     float64
+		state int
+	}{
+		{v: 15, state: NAGIOS_OK},
+		{v: 21, state: NAGIOS_WARNING},
+		{v: 9, state: NAGIOS_WARNING},
+		{v: 26, state: NAGIOS_CRITICAL},
+		{v: 4, state: NAGIOS_CRITICAL},
+	} {
+		state := ThresholdState(tc.v, warn, crit)
+		if state != tc.state {
+			t.Errorf("Value %v: expected state %d but got %d", tc.v, tc.state, state)
+		}
+	}
+
+	if ThresholdState(100, nil, nil) != NAGIOS_OK {
+		t.Errorf("Expected state OK without ranges")
+	}
+}
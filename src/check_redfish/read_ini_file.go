@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IniSection - key/value pairs of a section, in order of the file
+type IniSection struct {
+	Keys   []string
+	Values map[string]string
+}
+
+// ReadIniFile reads sections of an INI file, keys outside of a section are stored in section ""
+func ReadIniFile(f string) (map[string]*IniSection, error) {
+	var result = make(map[string]*IniSection)
+	var line_no int
+
+	/*
+	   Format:
+
+	       [section]
+	       key = value
+	       flag
+
+	   Notes:
+
+	       * empty lines and lines starting with # or ; are ignored
+	       * white spaces around keys and values are removed, values can be enclosed in single or double quotes
+	       * keys without a value (e.g. insecure-ssl) have an empty value
+	       * if a key is defined several times in a section the last value is used
+	*/
+	fd, err := os.Open(f)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	section := &IniSection{
+		Keys:   make([]string, 0),
+		Values: make(map[string]string),
+	}
+	result[""] = section
+
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line_no++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, errors.New(fmt.Sprintf("%s, line %d: Invalid section %s", f, line_no, line))
+			}
+
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			section = result[name]
+			if section == nil {
+				section = &IniSection{
+					Keys:   make([]string, 0),
+					Values: make(map[string]string),
+				}
+				result[name] = section
+			}
+			continue
+		}
+
+		var key string
+		var value string
+		splitted := strings.SplitN(line, "=", 2)
+		key = strings.TrimSpace(splitted[0])
+		if len(splitted) == 2 {
+			value = strings.TrimSpace(splitted[1])
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+		}
+
+		if key == "" {
+			return nil, errors.New(fmt.Sprintf("%s, line %d: Empty key", f, line_no))
+		}
+
+		if _, found := section.Values[key]; !found {
+			section.Keys = append(section.Keys, key)
+		}
+		section.Values[key] = value
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
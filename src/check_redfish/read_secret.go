@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ReadSecretFromEnv returns the value of environment variable name
+func ReadSecretFromEnv(name string) (string, error) {
+	value, found := os.LookupEnv(name)
+	if !found {
+		return "", errors.New(fmt.Sprintf("Environment variable %s is not set", name))
+	}
+
+	if value == "" {
+		return "", errors.New(fmt.Sprintf("Environment variable %s is empty", name))
+	}
+
+	return value, nil
+}
+
+// ReadSecretFromCommand runs command using /bin/sh and returns the first line of its standard output.
+// Standard error of the command is discarded, so secrets from error messages never show up in the plugin output.
+func ReadSecretFromCommand(command string, timeout time.Duration) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdin = nil
+	cmd.Stdout = &stdout
+	cmd.Stderr = nil
+	// run in a process group of its own, so children of the shell will be killed on timeout too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := cmd.Start()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Can't start command: %s", err.Error()))
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return "", errors.New(fmt.Sprintf("Command timed out after %s", timeout.String()))
+	}
+
+	if err != nil {
+		// only report the exit status, never the output of the command
+		return "", errors.New(fmt.Sprintf("Command failed: %s", err.Error()))
+	}
+
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Scan()
+	line := scanner.Text()
+	if line == "" {
+		return "", errors.New("Command returned an empty line")
+	}
+
+	return line, nil
+}
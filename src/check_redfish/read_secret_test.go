@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadSecretFromEnv(t *testing.T) {
+	t.Setenv("CHECK_REDFISH_TEST_PASSWORD", "secret")
+	t.Setenv("CHECK_REDFISH_TEST_EMPTY", "")
+
+	value, err := ReadSecretFromEnv("CHECK_REDFISH_TEST_PASSWORD")
+	if err != nil || value != "secret" {
+		t.Errorf("Expected secret but got %q, error %v", value, err)
+	}
+
+	for name, expected := range map[string]string{
+		"CHECK_REDFISH_TEST_EMPTY": "Environment variable CHECK_REDFISH_TEST_EMPTY is empty",
+		"CHECK_REDFISH_TEST_UNSET": "Environment variable CHECK_REDFISH_TEST_UNSET is not set",
+	} {
+		_, err = ReadSecretFromEnv(name)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s: expected error %q but got %v", name, expected, err)
+		}
+	}
+}
+
+func TestReadSecretFromCommand(t *testing.T) {
+	for _, tc := range []struct {
+		command string
+		value   string
+		err     string
+	}{
+		{command: "printf 'secret\\nsecond line\\n'", value: "secret"},
+		{command: "printf 'secret with spaces\\r\\n'", value: "secret with spaces"},
+		{command: "printf secret", value: "secret"},
+		{command: "printf '\\nsecret\\n'", err: "Command returned an empty line"},
+		{command: "true", err: "Command returned an empty line"},
+		// standard error and output of failed commands are never reported
+		{command: "echo secret; echo secret >&2; exit 3", err: "Command failed: exit status 3"},
+		{command: "/nonexistent/command", err: "Command failed: exit status 127"},
+	} {
+		value, err := ReadSecretFromCommand(tc.command, 5*time.Second)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: expected error %q but got %v", tc.command, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.command, err.Error())
+		} else if value != tc.value {
+			t.Errorf("%s: expected %q but got %q", tc.command, tc.value, value)
+		}
+	}
+}
+
+func TestReadSecretFromCommandTimeout(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "late")
+
+	// the background process is a child of the shell and must be killed with the shell
+	start := time.Now()
+	_, err := ReadSecretFromCommand("(sleep 1; echo late > "+f+") & sleep 10", 200*time.Millisecond)
+	if err == nil || !strings.HasPrefix(err.Error(), "Command timed out after 200ms") {
+		t.Errorf("Expected a timeout but got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Command has not been killed on timeout")
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	_, err = os.Stat(f)
+	if err == nil {
+		t.Errorf("Background process of the command has not been killed")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FixtureFile returns the file in dir storing the response of endpoint, e.g. <dir>/redfish/v1/Chassis/1/Thermal.json
+// for /redfish/v1/Chassis/1/Thermal
+func FixtureFile(dir string, endpoint string) (string, error) {
+	// query strings aren't used by the checks
+	endpoint = strings.SplitN(endpoint, "?", 2)[0]
+
+	cleaned := path.Clean("/" + endpoint)
+	if cleaned == "/" {
+		return "", errors.New(fmt.Sprintf("Invalid endpoint %s", endpoint))
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(cleaned)) + ".json", nil
+}
+
+// RecordResponse stores the response of a GET request for endpoint in dir, readable by the owner only
+func RecordResponse(dir string, endpoint string, content []byte) error {
+	f, err := FixtureFile(dir, endpoint)
+	if err != nil {
+		return err
+	}
+
+	// responses may contain sensitive data like serial numbers or MAC addresses
+	return WriteFileAtomic(f, content, 0600)
+}
+
+// ReplayResponse returns the response of a GET request for endpoint recorded in dir.
+// Missing endpoints are reported as HTTP status 404.
+func ReplayResponse(dir string, endpoint string) ([]byte, error) {
+	f, err := FixtureFile(dir, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(f)
+	if os.IsNotExist(err) {
+		return nil, HttpStatusError{
+			Method:     "GET",
+			Url:        endpoint,
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found (not recorded)",
+		}
+	}
+	return content, err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordReplayResponse(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fixtures")
+
+	err := RecordResponse(dir, "/redfish/v1/Chassis/1/Thermal?$expand=.", []byte(`{"Id": "Thermal"}`))
+	if err != nil {
+		t.Fatalf("Recording failed: %s", err.Error())
+	}
+
+	content, err := ReplayResponse(dir, "/redfish/v1/Chassis/1/Thermal")
+	if err != nil {
+		t.Fatalf("Replay failed: %s", err.Error())
+	}
+	if string(content) != `{"Id": "Thermal"}` {
+		t.Errorf("Unexpected content %s", content)
+	}
+
+	for f, mode := range map[string]os.FileMode{
+		dir: 0700 | os.ModeDir,
+		filepath.Join(dir, "redfish", "v1", "Chassis", "1"):                 0700 | os.ModeDir,
+		filepath.Join(dir, "redfish", "v1", "Chassis", "1", "Thermal.json"): 0600,
+	} {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Errorf("%s: %s", f, err.Error())
+		} else if info.Mode() != mode {
+			t.Errorf("%s: expected mode %s but got %s", f, mode, info.Mode())
+		}
+	}
+
+	// no temporary files are left behind
+	files, _ := filepath.Glob(filepath.Join(dir, "redfish", "v1", "Chassis", "1", "*"))
+	hidden, _ := filepath.Glob(filepath.Join(dir, "redfish", "v1", "Chassis", "1", ".*"))
+	if len(files) != 1 || len(hidden) != 0 {
+		t.Errorf("Unexpected files %q %q", files, hidden)
+	}
+
+	_, err = ReplayResponse(dir, "/redfish/v1/Chassis/1/Power")
+	if e, ok := err.(HttpStatusError); !ok || e.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected HTTP status 404 for a missing response but got %v", err)
+	}
+
+	// responses are never written outside of the directory
+	err = RecordResponse(dir, "/../..", []byte("{}"))
+	if err == nil {
+		t.Errorf("Expected an error for an invalid endpoint")
+	}
+}
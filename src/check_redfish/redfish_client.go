@@ -0,0 +1,62 @@
+package main
+
+import (
+	redfish "git.ypbind.de/repository/go-redfish.git"
+)
+
+// RedfishClient - operations used by the checks to read data from the management board. BmcClient implements
+// them using HTTP, other implementations may e.g. cache or record the data or serve it from fixtures.
+type RedfishClient interface {
+	// GetChassis returns the endpoints of all chassis
+	GetChassis() ([]string, error)
+	GetChassisData(endpoint string) (*redfish.ChassisData, error)
+	// MapChassisById returns the data of all chassis, indexed by the Id of the chassis
+	MapChassisById() (map[string]*redfish.ChassisData, error)
+	GetThermalData(endpoint string) (*redfish.ThermalData, error)
+	GetPowerData(endpoint string) (*redfish.PowerData, error)
+	// GetSystems returns the endpoints of all systems
+	GetSystems() ([]string, error)
+	GetSystemData(endpoint string) (*redfish.SystemData, error)
+	// MapSystemsById returns the data of all systems, indexed by the Id of the system
+	MapSystemsById() (map[string]*redfish.SystemData, error)
+}
+
+// MapChassisById reads the data of all chassis reported by rf, indexed by the Id of the chassis.
+// Implementations of RedfishClient can use it to implement their MapChassisById method.
+func MapChassisById(rf RedfishClient) (map[string]*redfish.ChassisData, error) {
+	var result = make(map[string]*redfish.ChassisData)
+
+	cha_epl, err := rf.GetChassis()
+	if err != nil {
+		return result, err
+	}
+
+	for _, ep := range cha_epl {
+		chassis_data, err := rf.GetChassisData(ep)
+		if err != nil {
+			return result, err
+		}
+		result[ChassisId(chassis_data)] = chassis_data
+	}
+	return result, nil
+}
+
+// MapSystemsById reads the data of all systems reported by rf, indexed by the Id of the system.
+// Implementations of RedfishClient can use it to implement their MapSystemsById method.
+func MapSystemsById(rf RedfishClient) (map[string]*redfish.SystemData, error) {
+	var result = make(map[string]*redfish.SystemData)
+
+	sys_epl, err := rf.GetSystems()
+	if err != nil {
+		return result, err
+	}
+
+	for _, ep := range sys_epl {
+		system_data, err := rf.GetSystemData(ep)
+		if err != nil {
+			return result, err
+		}
+		result[SystemId(system_data)] = system_data
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ResponseCache - responses of GET requests stored in files per management board and user, shared by concurrent runs
+// (e.g. the services of a host scheduled independently). Only one run refreshes an expired resource, the other
+// runs wait for it and read the fresh copy.
+type ResponseCache struct {
+	Directory string
+	// responses older than Ttl are requested again
+	Ttl time.Duration
+	// maximal time to wait for another run refreshing a resource, the resource is requested anyway afterwards
+	LockWait time.Duration
+}
+
+// File returns the cache file of endpoint for user on the management board host:port,
+// e.g. <dir>/<host>_<port>_<user>/redfish/v1/Chassis/1/Thermal.json. The responses depend on the privileges
+// of the user, so users never share responses.
+func (rc *ResponseCache) File(host string, port int, user string, endpoint string) (string, error) {
+	board := unsafe_file_chars.ReplaceAllString(fmt.Sprintf("%s_%d_%s", host, port, user), "_")
+	return FixtureFile(filepath.Join(rc.Directory, board), endpoint)
+}
+
+// readFresh returns the content of f if it is younger than Ttl, nil otherwise
+func (rc *ResponseCache) readFresh(f string) []byte {
+	info, err := os.Stat(f)
+	if err != nil || time.Since(info.ModTime()) >= rc.Ttl {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// lock locks the cache file f, waiting at most LockWait for another run refreshing it
+func (rc *ResponseCache) lock(f string) (*FileLock, error) {
+	err := os.MkdirAll(filepath.Dir(f), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return LockFile(f+".lock", rc.LockWait)
+}
+
+// cachedGet returns the response of endpoint from the response cache if it is fresh, otherwise it requests
+// endpoint while holding the lock of the resource and stores the response
+func (c *BmcClient) cachedGet(endpoint string) ([]byte, error) {
+	rc := c.ResponseCache
+
+	f, err := rc.File(c.Hostname, c.Port, c.Username, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	content := rc.readFresh(f)
+	if content != nil {
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "GET %s answered from %s", endpoint, f)
+		return content, nil
+	}
+
+	lock, err := rc.lock(f)
+	if err != nil {
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "Can't lock %s, requesting it without lock: %s", f, err.Error())
+	} else {
+		defer lock.Unlock()
+
+		// another run may have refreshed the resource while we were waiting for the lock
+		content = rc.readFresh(f)
+		if content != nil {
+			Trace(c.Verbosity, VERBOSE_REQUESTS, "GET %s answered from %s", endpoint, f)
+			return content, nil
+		}
+	}
+
+	content, err = c.get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// a broken cache must not break the check
+	err = WriteFileAtomic(f, content, 0600)
+	if err != nil {
+		Trace(c.Verbosity, VERBOSE_REQUESTS, "Can't store response of %s in %s: %s", endpoint, f, err.Error())
+	}
+
+	return content, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newCachedBmcClient returns an initialised client for m using the response cache in dir, logging in on demand
+func newCachedBmcClient(t *testing.T, m *MockBmc, dir string, ttl time.Duration) *BmcClient {
+	rf := m.NewClient()
+	rf.ResponseCache = &ResponseCache{Directory: dir, Ttl: ttl, LockWait: 5 * time.Second}
+	rf.OnDemandLogin = rf.Login
+	t.Cleanup(func() { rf.Logout() })
+
+	err := rf.Initialise()
+	if err != nil {
+		t.Fatalf("Initialisation failed: %s", err.Error())
+	}
+	return rf
+}
+
+func countRequests(m *MockBmc, request string) int {
+	var count int
+	for _, r := range m.Requests() {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestResponseCache(t *testing.T) {
+	m := NewMockBmc(t, "dell_idrac", nil)
+	dir := t.TempDir()
+
+	first, err := CheckThermal(newCachedBmcClient(t, m, dir, time.Minute), ALL_MEMBERS, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Check failed: %s", err.Error())
+	}
+	requests := len(m.Requests())
+
+	second, err := CheckThermal(newCachedBmcClient(t, m, dir, time.Minute), ALL_MEMBERS, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Check using the cache failed: %s", err.Error())
+	}
+
+	if len(m.Requests()) != requests {
+		t.Errorf("Fresh responses have been requested again: %q", m.Requests()[requests:])
+	}
+	if countRequests(m, "POST /redfish/v1/SessionService/Sessions") != 1 {
+		t.Errorf("Expected a single login but got %q", m.Requests())
+	}
+	if NagiosStateRc(first) != NagiosStateRc(second) || len(first.PerfData) != len(second.PerfData) {
+		t.Errorf("Cached result %+v differs from %+v", second, first)
+	}
+}
+
+func TestResponseCacheExpired(t *testing.T) {
+	m := NewMockBmc(t, "supermicro", nil)
+	dir := t.TempDir()
+
+	rf := newCachedBmcClient(t, m, dir, time.Minute)
+	_, err := rf.GetThermalData("/redfish/v1/Chassis/1/Thermal")
+	if err != nil {
+		t.Fatalf("Request failed: %s", err.Error())
+	}
+
+	f, _ := rf.ResponseCache.File(rf.Hostname, rf.Port, rf.Username, "/redfish/v1/Chassis/1/Thermal")
+	old := time.Now().Add(-2 * time.Minute)
+	os.Chtimes(f, old, old)
+
+	_, err = newCachedBmcClient(t, m, dir, time.Minute).GetThermalData("/redfish/v1/Chassis/1/Thermal")
+	if err != nil {
+		t.Fatalf("Request failed: %s", err.Error())
+	}
+
+	if countRequests(m, "GET /redfish/v1/Chassis/1/Thermal") != 2 {
+		t.Errorf("Expired response has not been requested again: %q", m.Requests())
+	}
+}
+
+func TestResponseCacheErrors(t *testing.T) {
+	m := NewMockBmc(t, "supermicro", map[string]string{"/redfish/v1/Chassis/1/Thermal": ""})
+	dir := t.TempDir()
+
+	for i := 0; i < 2; i++ {
+		_, err := newCachedBmcClient(t, m, dir, time.Minute).GetThermalData("/redfish/v1/Chassis/1/Thermal")
+		if err == nil {
+			t.Fatalf("Expected an error for the missing Thermal endpoint")
+		}
+	}
+
+	// errors aren't cached
+	if countRequests(m, "GET /redfish/v1/Chassis/1/Thermal") != 2 {
+		t.Errorf("Expected 2 requests of the missing Thermal endpoint but got %q", m.Requests())
+	}
+}
+
+func TestResponseCacheConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+
+	m := NewMockBmc(t, "lenovo_xcc", nil)
+	dir := t.TempDir()
+
+	for i := 0; i < 5; i++ {
+		rf := newCachedBmcClient(t, m, dir, time.Minute)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := rf.GetPowerData("/redfish/v1/Chassis/1/Power")
+			if err != nil {
+				t.Errorf("Request failed: %s", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// only one client refreshes the resource, the others read its response
+	if countRequests(m, "GET /redfish/v1/Chassis/1/Power") != 1 {
+		t.Errorf("Expected a single request of the Power endpoint but got %q", m.Requests())
+	}
+}
+
+func TestResponseCacheUsers(t *testing.T) {
+	m := NewMockBmc(t, "dell_idrac", nil)
+	dir := t.TempDir()
+
+	_, err := newCachedBmcClient(t, m, dir, time.Minute).GetThermalData("/redfish/v1/Chassis/System.Embedded.1/Thermal")
+	if err != nil {
+		t.Fatalf("Request failed: %s", err.Error())
+	}
+
+	// another user must log in and request the resource, even if it is fresh in the cache of the first user
+	rf := m.NewClient()
+	rf.Username = "operator"
+	rf.ResponseCache = &ResponseCache{Directory: dir, Ttl: time.Minute, LockWait: 5 * time.Second}
+	rf.OnDemandLogin = rf.Login
+
+	err = rf.Initialise()
+	if err != nil {
+		t.Fatalf("Initialisation failed: %s", err.Error())
+	}
+
+	_, err = rf.GetThermalData("/redfish/v1/Chassis/System.Embedded.1/Thermal")
+	if e, ok := err.(HttpStatusError); !ok || e.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the login of the unknown user to fail but got %v", err)
+	}
+	if countRequests(m, "POST /redfish/v1/SessionService/Sessions") != 2 {
+		t.Errorf("Expected a login of each user but got %q", m.Requests())
+	}
+
+	first, _ := rf.ResponseCache.File(rf.Hostname, rf.Port, MOCK_USER, "/redfish/v1/")
+	second, _ := rf.ResponseCache.File(rf.Hostname, rf.Port, "operator", "/redfish/v1/")
+	if first == second {
+		t.Errorf("Users share the cache file %s", first)
+	}
+}
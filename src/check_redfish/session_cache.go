@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// SessionCacheEntry - Redfish session stored in the session cache
+type SessionCacheEntry struct {
+	AuthToken       string    `json:"auth_token"`
+	SessionLocation string    `json:"session_location"`
+	Created         time.Time `json:"created"`
+}
+
+var unsafe_file_chars = regexp.MustCompile(`[^a-zA-Z0-9.@_-]`)
+
+// SessionCacheFile returns the name of the cache file for a host, port and user in directory dir
+func SessionCacheFile(dir string, host string, port int, user string) string {
+	name := fmt.Sprintf("%s_%d_%s.session", host, port, user)
+	return filepath.Join(dir, unsafe_file_chars.ReplaceAllString(name, "_"))
+}
+
+func ReadSessionCache(f string) (*SessionCacheEntry, error) {
+	var entry SessionCacheEntry
+
+	raw, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, &entry)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.AuthToken == "" {
+		return nil, errors.New(fmt.Sprintf("No authentication token found in %s", f))
+	}
+
+	return &entry, nil
+}
+
+// WriteSessionCache stores the session in file f, readable only by the owner
+func WriteSessionCache(f string, entry SessionCacheEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(f, raw, 0600)
+}
+
+// lockSessionCache locks the session cache file f, waiting at most wait for another run logging in
+func lockSessionCache(f string, wait time.Duration) (*FileLock, error) {
+	err := os.MkdirAll(filepath.Dir(f), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return LockFile(f+".lock", wait)
+}
+
+// LoginWithSessionCache reuses the session stored in the cache directory dir if it is still valid, otherwise
+// it logs in and stores the new session. Returns true if the session has been stored in the cache and should be kept
+// (no logout) for the next run.
+func LoginWithSessionCache(rf *BmcClient, dir string) (bool, error) {
+	f := SessionCacheFile(dir, rf.Hostname, rf.Port, rf.Username)
+
+	// concurrent runs would each log in and overwrite the session of the others, which would never be closed
+	lock, err := lockSessionCache(f, rf.Timeout)
+	if err != nil {
+		// the session can't be shared safely, it must be closed at the end of this run
+		return false, rf.Login()
+	}
+	defer lock.Unlock()
+
+	entry, err := ReadSessionCache(f)
+	if err == nil {
+		token := entry.AuthToken
+		location := entry.SessionLocation
+		rf.AuthToken = &token
+		rf.SessionLocation = &location
+
+		// only log in again if the session was rejected
+		err = rf.ValidateSession()
+		if err == nil {
+			return true, nil
+		}
+
+		if e, ok := err.(HttpStatusError); !ok || e.StatusCode != http.StatusUnauthorized {
+			return true, err
+		}
+
+		rf.AuthToken = nil
+		rf.SessionLocation = nil
+		os.Remove(f)
+	}
+
+	err = rf.Login()
+	if err != nil {
+		return false, err
+	}
+
+	entry = &SessionCacheEntry{
+		Created: time.Now(),
+	}
+	if rf.AuthToken != nil {
+		entry.AuthToken = *rf.AuthToken
+	}
+	if rf.SessionLocation != nil {
+		entry.SessionLocation = *rf.SessionLocation
+	}
+
+	// if the session can't be stored it must be closed at the end of this run
+	err = WriteSessionCache(f, *entry)
+	if err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLoginWithSessionCacheConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	var start = make(chan bool)
+
+	m := NewMockBmc(t, "dell_idrac", nil)
+	dir := t.TempDir()
+
+	for i := 0; i < 5; i++ {
+		rf := m.NewClient()
+		err := rf.Initialise()
+		if err != nil {
+			t.Fatalf("Initialisation failed: %s", err.Error())
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			keep, err := LoginWithSessionCache(rf, dir)
+			if err != nil {
+				t.Errorf("Login failed: %s", err.Error())
+			}
+			if !keep {
+				t.Errorf("Session has not been stored in the cache")
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// all runs share the session created by the first one
+	if m.Sessions() != 1 {
+		t.Errorf("Expected 1 session but got %d: %q", m.Sessions(), m.Requests())
+	}
+}
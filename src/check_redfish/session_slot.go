@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// DefaultLockDir returns the default directory of the lock files of -max-sessions, a directory per user
+// in the directory for temporary files
+func DefaultLockDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("check_redfish-%d", os.Getuid()))
+}
+
+// checkLockDir refuses directories other users can write to (e.g. a directory in /tmp created by another user
+// to hold or manipulate the slots), dir must be a directory owned by the user with mode 0700
+func checkLockDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || int(stat.Uid) != os.Getuid() || info.Mode().Perm() != 0700 {
+		return errors.New(fmt.Sprintf("Lock directory %s must be a directory owned by uid %d with mode 0700", dir, os.Getuid()))
+	}
+
+	return nil
+}
+
+// SessionSlotFile returns the lock file of slot of the management board host:port in directory dir
+func SessionSlotFile(dir string, host string, port int, slot uint) string {
+	name := fmt.Sprintf("check_redfish_%s_%d.%d.lock", host, port, slot)
+	return filepath.Join(dir, unsafe_file_chars.ReplaceAllString(name, "_"))
+}
+
+// AcquireSessionSlot limits the number of concurrent runs for the management board host:port to max by locking
+// one of max lock files in dir, waiting at most wait for a free slot. The slot is released by Unlock of
+// the returned lock or when the process exits.
+func AcquireSessionSlot(dir string, host string, port int, max uint, wait time.Duration) (*FileLock, error) {
+	var last_err error
+	give_up := time.Now().Add(wait)
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkLockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		for slot := uint(0); slot < max; slot++ {
+			lock, err := TryLockFile(SessionSlotFile(dir, host, port, slot))
+			if err != nil {
+				// e.g. a symbolic link, the other slots may still be usable
+				last_err = err
+				continue
+			}
+			if lock != nil {
+				return lock, nil
+			}
+		}
+
+		if time.Now().After(give_up) {
+			if last_err != nil {
+				return nil, errors.New(fmt.Sprintf("No session slot for %s available after %s: %s", host, wait.String(), last_err.Error()))
+			}
+			return nil, errors.New(fmt.Sprintf("All %d session slots for %s are in use, gave up after %s", max, host, wait.String()))
+		}
+		time.Sleep(LOCK_POLL_INTERVAL)
+	}
+}
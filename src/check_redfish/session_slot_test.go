@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireSessionSlot(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "locks")
+
+	first, err := AcquireSessionSlot(dir, "bmc", 443, 2, time.Second)
+	if err != nil {
+		t.Fatalf("Can't acquire the first slot: %s", err.Error())
+	}
+
+	second, err := AcquireSessionSlot(dir, "bmc", 443, 2, time.Second)
+	if err != nil {
+		t.Fatalf("Can't acquire the second slot: %s", err.Error())
+	}
+
+	start := time.Now()
+	_, err = AcquireSessionSlot(dir, "bmc", 443, 2, 300*time.Millisecond)
+	if err == nil {
+		t.Errorf("Acquired more slots than allowed")
+	}
+	if time.Since(start) < 300*time.Millisecond {
+		t.Errorf("Gave up after %s instead of waiting for a free slot", time.Since(start).String())
+	}
+
+	// slots of other management boards are independent
+	other, err := AcquireSessionSlot(dir, "other-bmc", 443, 2, 0)
+	if err != nil {
+		t.Errorf("Slots of another management board are in use: %s", err.Error())
+	} else {
+		other.Unlock()
+	}
+
+	// a waiting run gets the slot as soon as it is released
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		first.Unlock()
+	}()
+
+	third, err := AcquireSessionSlot(dir, "bmc", 443, 2, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Released slot has not been acquired: %s", err.Error())
+	}
+
+	third.Unlock()
+	second.Unlock()
+}
+
+func TestAcquireSessionSlotUnusable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "locks")
+	err := os.Mkdir(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slot 0 can't be opened, symbolic links aren't followed
+	err = os.Symlink(filepath.Join(dir, "missing", "slot"), SessionSlotFile(dir, "bmc", 443, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := AcquireSessionSlot(dir, "bmc", 443, 2, time.Second)
+	if err != nil {
+		t.Fatalf("Usable slot has not been acquired: %s", err.Error())
+	}
+
+	_, err = AcquireSessionSlot(dir, "bmc", 443, 2, 200*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "too many levels of symbolic links") {
+		t.Errorf("Expected the error of the unusable slot but got %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestAcquireSessionSlotCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "locks")
+
+	lock, err := AcquireSessionSlot(dir, "bmc", 443, 1, 0)
+	if err != nil {
+		t.Fatalf("Can't acquire a slot: %s", err.Error())
+	}
+	defer lock.Unlock()
+
+	for f, mode := range map[string]os.FileMode{dir: 0700 | os.ModeDir, SessionSlotFile(dir, "bmc", 443, 0): 0600} {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Errorf("%s: %s", f, err.Error())
+		} else if info.Mode() != mode {
+			t.Errorf("%s: expected mode %s but got %s", f, mode, info.Mode())
+		}
+	}
+}
+
+func TestAcquireSessionSlotUnsafeDir(t *testing.T) {
+	base := t.TempDir()
+
+	shared := filepath.Join(base, "shared")
+	err := os.Mkdir(shared, 0700)
+	if err == nil {
+		// mode of the directory isn't affected by umask
+		err = os.Chmod(shared, 0777)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	private := filepath.Join(base, "private")
+	err = os.Mkdir(private, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(base, "link")
+	err = os.Symlink(private, link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := []string{shared, link}
+
+	// only root can create directories owned by other users
+	if os.Getuid() == 0 {
+		other := filepath.Join(base, "other")
+		err = os.Mkdir(other, 0700)
+		if err == nil {
+			err = os.Chown(other, 65534, 65534)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs = append(dirs, other)
+	}
+
+	for _, dir := range dirs {
+		_, err = AcquireSessionSlot(dir, "bmc", 443, 1, 0)
+		if err == nil || !strings.Contains(err.Error(), "must be a directory owned by uid") {
+			t.Errorf("%s: expected the lock directory to be refused but got %v", dir, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const help string = `redfish-tool version 1.0.2
 Copyright (C) 2018 - 2019 by Andreas Maus <maus@ypbind.de>
@@ -9,42 +12,170 @@ This program comes with ABSOLUTELY NO WARRANTY.
 redfish-tool is distributed under the Terms of the GNU General
 Public License Version 3. (http://www.gnu.org/copyleft/gpl.html)
 
-Usage: check_redfish -host=<host> -user=<user> -password=<pass>|-password-file=<pwdfile> [-insecure-ssl] 
-    [-chassis-id=<id>] [-system-id=<id>] [-check-installed-memory=<mem_gb>] [-timeout=<sec>] 
-    [-check-installed-cpus=<cpu>] [-check-termal] [-check-psu=<warn>,<crit>] [-check-general-health]
-
+Usage: check_redfish -host=<host>|-listen=<addr> -user=<user>|-user-env=<var>|-user-command=<cmd>
+    -password=<pass>|-password-file=<pwdfile>|-password-env=<var>|-password-command=<cmd> [-secret-timeout=<sec>]
+    [-insecure-ssl] [-ca-file=<file>] [-client-cert=<file> -client-key=<file>] [-fingerprint=<sha256>]
+    [-auth=session|basic] [-proxy=<url>]
+    [-chassis-id=<id>] [-system-id=<id>] [-timeout=<sec>] [-deadline=<sec>] [-rules=<file>]
+    [-retries=<n>] [-retry-delay=<sec>] [-v|-vv|-vvv]
+    [-output=short|long|json] [-config=<file>] [-session-cache=<dir>]
+    [-response-cache=<dir> [-response-cache-ttl=<sec>]]
+    [-max-sessions=<n> [-max-sessions-wait=<sec>] [-lock-dir=<dir>]]
+    [-record=<dir>|-replay=<dir>] [--extra-opts=[<section>][@<file>]]
+%s
     -host=<host>
         Hostname or IP address of management board
+    -listen=<addr>
+        Run as Prometheus exporter on <addr> (e.g. :9610) instead of running checks. Metrics of a
+        management board are available at http://<addr>/metrics?target=<host>
     -user=<user>
         Username for authentication
     -password=<pass>
         Password for authentication
     -password-file=<pwdfile>
         Read password for authentication from file, password is exepected in the first line of the file
+    -password-env=<var>
+        Read password for authentication from environment variable <var>
+    -password-command=<cmd>
+        Run <cmd> using /bin/sh and read the password from the first line of its output, e.g. a password manager.
+        Standard error of <cmd> is discarded. If several password options are given, -password-command takes
+        precedence over -password-env, -password-file and -password
+    -user-env=<var>
+        Read username for authentication from environment variable <var>
+    -user-command=<cmd>
+        Run <cmd> using /bin/sh and read the username from the first line of its output
+    -secret-timeout=<sec>
+        Timeout for -user-command and -password-command in seconds. Default: 10
     -insecure-ssl
         Don't verifiy SSL certificate. Default: Verify SSL certificate
+    -ca-file=<file>
+        Verify the SSL certificate using the PEM encoded CA certificates from <file> instead of the system
+        trust store, e.g. of an internal PKI
+    -client-cert=<file>
+        PEM encoded client certificate for management boards requiring mutual TLS, requires -client-key
+    -client-key=<file>
+        PEM encoded key of the client certificate
+    -fingerprint=<sha256>
+        Accept only the SSL certificate with the SHA256 fingerprint <sha256> (hexadecimal, colons are
+        optional) instead of verifying the certificate chain and the host name, e.g. for self-signed
+        certificates. The fingerprint can be shown by: openssl x509 -noout -fingerprint -sha256 -in <cert>
+    -proxy=<url>
+        Connect to the management board through the proxy <url>, either an HTTP proxy (http://<host>:<port>)
+        or a SOCKS5 proxy (socks5://<host>:<port>), e.g. a jump host running "ssh -D <port>". Credentials
+        can be given as http://<user>:<pass>@<host>:<port>. Set proxy in the host section of -config to use
+        different proxies per management board. Errors of the proxy are reported as "Proxy <url> failed".
+    -auth=session|basic
+        Authentication method. session logs in using the SessionService and logs out at the end, basic sends
+        username and password on every request using HTTP basic authentication and doesn't create a session
+        (-session-cache is ignored). Default: session
     -chassis-id=<id>
-        Check specific chassis. Default: First chassis reported will be checked
+        Check specific chassis, "all" checks every chassis. Default: First chassis reported will be checked
     -system-id=<id>
-        Check specific system. Default: First system reported will be checked
+        Check specific system, "all" checks every system. Default: First system reported will be checked
     -timeout=<sec>
         Connection timeout in seconds. Default: 60
-    -check-installed-memory=<mem_gb>
-        Check if installed memory is recognized with <mem_gb> GByte of memory
-    -check-installed-cpus=<cpu>
-        Check number of installed cpus
-    -check-thermal
-        Check thermal subsystem
-    -check-fans
-        Check system fans
-    -check-psu=<warn>,<crit>
-        Check installed PSU, report <warn>/<crit> if <warn>/<crit> or less working PSUs are reported
-    -check-voltages
-        Check system voltage readings
-    -check-general-health
-        Check general health. This is the default when no check has been requested
-`
+    -deadline=<sec>
+        Overall timeout of the plugin run in seconds. If it expires the result is UNKNOWN, reporting the
+        phase the run was in (e.g. login or check thermal), and the session is closed. It should be a bit
+        lower than the service_check_timeout of Nagios. SIGTERM and SIGINT close the session as well.
+        Default: 0 (no overall timeout)
+    -retries=<n>
+        Retry requests reading data and the login up to <n> times if the management board answers with
+        HTTP status 429, 502, 503 or 504 or the connection fails. Retries are only started if they can finish
+        before the -deadline expires. -output=long and -output=json report the number of retries. Default: 0
+    -retry-delay=<sec>
+        Delay before the first retry in seconds, the delay is doubled for every further retry. Default: 1
+    -output=short|long|json
+        Output format. short reports all results in a single line, long reports a summary in the first line
+        and one line per result, grouped by severity, json reports the results and details of the checked
+        components as JSON document. Default: short
+    -v|-vv|-vvv
+        Log the Redfish requests to standard error. -v logs the requested URLs, status codes, timings and
+        retries, -vv adds the request and response headers and -vvv the raw JSON bodies. Passwords,
+        X-Auth-Token and Authorization headers and the bodies of the login request are always redacted.
+    -record=<dir>
+        Store the responses of all GET requests in <dir>, one JSON file per URI (e.g.
+        <dir>/redfish/v1/Chassis/1/Thermal.json), e.g. to attach the data of a management board to a bug report.
+        Only the data read by the requested checks is stored, use -chassis-id=all -system-id=all and request
+        every check to record all data.
+    -replay=<dir>
+        Run the checks against the responses stored by -record in <dir> without connecting to the management
+        board. -host, -user and -password aren't required. Missing URIs are reported as HTTP status 404.
+    -session-cache=<dir>
+        Store the Redfish session in <dir> (readable by the owner only) and reuse it in later runs instead of
+        logging in and out every time. A new session is created if the management board rejects the stored session.
+    -response-cache=<dir>
+        Store the responses of the management board in <dir> (one subdirectory per host and user) and use them in
+        later or concurrent runs, e.g. by the other services of the host, instead of requesting them again.
+        Only one run refreshes an expired response, concurrent runs wait at most -timeout for it. No session is
+        created if all responses are found in the cache. Can't be used together with -record or -replay
+    -response-cache-ttl=<sec>
+        Time in seconds responses stored in -response-cache are used. Default: 60
+    -max-sessions=<n>
+        Allow at most <n> concurrent runs (and therefore sessions) per management board, further runs wait for a
+        free slot before logging in, e.g. for management boards refusing logins if too many sessions are open.
+        Runs answered completely from -response-cache don't need a slot. Not used in exporter mode.
+        Default: 0 (no limit)
+    -max-sessions-wait=<sec>
+        Maximal time in seconds to wait for a free slot of -max-sessions, the waiting time counts towards
+        -deadline. Default: 30
+    -lock-dir=<dir>
+        Directory for the lock files of -max-sessions, must be the same for all runs. <dir> must be owned by
+        the user running the plugin with mode 0700 (created if missing), so runs of different users never share
+        the slots. Default: check_redfish-<uid> in the directory for temporary files, e.g. /tmp/check_redfish-1000
+    -config=<file>
+        Read options from INI file <file>. Keys are the names of the options (e.g. user, password-file,
+        insecure-ssl, timeout or thermal-warning), values of the [default] section are used for all hosts,
+        values of the section named by -host=<host> for this host only. If the section contains a host key
+        its value is used as host name. Options on the command line take precedence.
+    --extra-opts=[<section>][@<file>]
+        Read options from <section> (default: check_redfish) of the INI file <file> as described at
+        https://www.monitoring-plugins.org/doc/extra-opts.html. Without <file> the first plugins.ini found in
+        $NAGIOS_CONFIG_PATH or the default locations (e.g. /etc/nagios/plugins.ini) is used.
+        Options on the command line take precedence.
+    -rules=<file>
+        Read warning and critical ranges per sensor from <file>. Each line contains
+        <check> <pattern> <warning> <critical>, e.g. thermal "CPU*" ~:80 ~:90
+        <pattern> is a shell glob or a regular expression enclosed in slashes, - keeps the threshold
+        reported by the management board.
+        The first matching rule overrides the thresholds of the sensor.
+
+    Several checks can be requested at once, they will share the same session and the worst state will be reported.
+
+%s`
+
+func checkOption(c Check) string {
+	arg, _ := c.Usage()
+	if arg == "" {
+		return "-check-" + c.Name()
+	}
+	return "-check-" + c.Name() + "=" + arg
+}
 
 func ShowUsage() {
-	fmt.Println(help)
+	var synopsis []string
+	var line []string
+	var options string
+
+	for _, c := range RegisteredChecks() {
+		_, descr := c.Usage()
+		options += fmt.Sprintf("    %s\n        %s\n", checkOption(c), descr)
+		if co, ok := c.(CheckWithOptions); ok {
+			for _, o := range co.Options() {
+				options += fmt.Sprintf("    %s\n        %s\n", o.Option, o.Help)
+			}
+		}
+
+		// keep the synopsis lines short
+		line = append(line, "["+checkOption(c)+"]")
+		if len(strings.Join(line, " ")) > 80 {
+			synopsis = append(synopsis, "    "+strings.Join(line, " ")+"\n")
+			line = make([]string, 0)
+		}
+	}
+	if len(line) > 0 {
+		synopsis = append(synopsis, "    "+strings.Join(line, " ")+"\n")
+	}
+
+	fmt.Printf(help, strings.Join(synopsis, ""), options)
 }
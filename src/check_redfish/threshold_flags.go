@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// ThresholdFlags - -<name>-warning and -<name>-critical options of a check
+type ThresholdFlags struct {
+	warn_str string
+	crit_str string
+	Warning  *Range
+	Critical *Range
+}
+
+// RegisterThresholdFlags adds -<name>-warning and -<name>-critical options
+func (t *ThresholdFlags) RegisterThresholdFlags(name string) {
+	flag.StringVar(&t.warn_str, name+"-warning", "", "Warning range for "+name+" readings")
+	flag.StringVar(&t.crit_str, name+"-critical", "", "Critical range for "+name+" readings")
+}
+
+// ParseThresholdFlags converts the values of the options to ranges
+func (t *ThresholdFlags) ParseThresholdFlags(name string) error {
+	var err error
+
+	if t.warn_str != "" {
+		t.Warning, err = ParseRange(t.warn_str)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid value for -%s-warning: %s", name, err.Error()))
+		}
+	}
+
+	if t.crit_str != "" {
+		t.Critical, err = ParseRange(t.crit_str)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid value for -%s-critical: %s", name, err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// ThresholdOptions returns the usage of -<name>-warning and -<name>-critical options
+func (t *ThresholdFlags) ThresholdOptions(name string, unit string) []CheckOption {
+	return []CheckOption{
+		{
+			Option: fmt.Sprintf("-%s-warning=<range>", name),
+			Help:   fmt.Sprintf("Report warning if a reading in %s is outside of <range> (e.g. 10:20, ~:50, @5:10). Default: Use thresholds reported by the management board", unit),
+		},
+		{
+			Option: fmt.Sprintf("-%s-critical=<range>", name),
+			Help:   fmt.Sprintf("Report critical if a reading in %s is outside of <range> (e.g. 10:20, ~:50, @5:10). Default: Use thresholds reported by the management board", unit),
+		},
+	}
+}
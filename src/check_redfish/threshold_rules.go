@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ThresholdRule - warning and critical range for sensors of a check matching a glob or a regular expression
+type ThresholdRule struct {
+	Check    string
+	Pattern  string
+	regex    *regexp.Regexp
+	Warning  *Range
+	Critical *Range
+}
+
+// ThresholdRules - list of rules, the first matching rule will be applied
+type ThresholdRules struct {
+	Rules []ThresholdRule
+}
+
+// ReadThresholdRules reads threshold rules from file f
+func ReadThresholdRules(f string) (*ThresholdRules, error) {
+	var rules = ThresholdRules{
+		Rules: make([]ThresholdRule, 0),
+	}
+	var line_no int
+
+	/*
+	   Rules are read from a file, one rule per line:
+
+	       <check> <pattern> <warning> <critical>
+
+	   Notes:
+
+	       * empty lines and lines starting with # are ignored
+	       * <check> is the name of the check, e.g. thermal, fans or voltages
+	       * <pattern> is a shell glob matching the whole sensor name (e.g. "CPU*", * also matches /) or a regular expression enclosed in slashes (e.g. /^Inlet/)
+	       * <warning> and <critical> are ranges in the threshold format, - keeps the threshold reported by the management board
+	       * fields containing spaces must be enclosed in double quotes
+	       * rules are applied in the order of the file, the first matching rule wins
+	*/
+
+	fd, err := os.Open(f)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line_no++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := parseThresholdRule(line)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("%s, line %d: %s", f, line_no, err.Error()))
+		}
+		rules.Rules = append(rules.Rules, rule)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+func parseThresholdRule(line string) (ThresholdRule, error) {
+	var rule ThresholdRule
+	var err error
+
+	fields, err := splitQuotedFields(line)
+	if err != nil {
+		return rule, err
+	}
+
+	if len(fields) != 4 {
+		return rule, errors.New(fmt.Sprintf("Expected 4 fields but found %d", len(fields)))
+	}
+
+	rule.Check = fields[0]
+	if !isRegisteredCheck(rule.Check) {
+		return rule, errors.New(fmt.Sprintf("Unknown check %s", rule.Check))
+	}
+
+	rule.Pattern = fields[1]
+	if len(rule.Pattern) > 1 && strings.HasPrefix(rule.Pattern, "/") && strings.HasSuffix(rule.Pattern, "/") {
+		rule.regex, err = regexp.Compile(rule.Pattern[1 : len(rule.Pattern)-1])
+		if err != nil {
+			return rule, errors.New(fmt.Sprintf("Invalid regular expression %s: %s", rule.Pattern, err.Error()))
+		}
+	} else {
+		rule.regex, err = globToRegexp(rule.Pattern)
+		if err != nil {
+			return rule, errors.New(fmt.Sprintf("Invalid pattern %s: %s", rule.Pattern, err.Error()))
+		}
+	}
+
+	if fields[2] != "-" {
+		rule.Warning, err = ParseRange(fields[2])
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	if fields[3] != "-" {
+		rule.Critical, err = ParseRange(fields[3])
+		if err != nil {
+			return rule, err
+		}
+	}
+
+	return rule, nil
+}
+
+// splitQuotedFields splits a line at white spaces, double quoted fields may contain white spaces
+func splitQuotedFields(line string) ([]string, error) {
+	var result = make([]string, 0)
+	var field strings.Builder
+	var quoted bool
+	var in_field bool
+
+	for _, c := range line {
+		if c == '"' {
+			quoted = !quoted
+			in_field = true
+			continue
+		}
+
+		if !quoted && (c == ' ' || c == '\t') {
+			if in_field {
+				result = append(result, field.String())
+				field.Reset()
+				in_field = false
+			}
+			continue
+		}
+
+		field.WriteRune(c)
+		in_field = true
+	}
+
+	if quoted {
+		return nil, errors.New("Unterminated double quote")
+	}
+
+	if in_field {
+		result = append(result, field.String())
+	}
+
+	return result, nil
+}
+
+// globToRegexp converts a shell glob to an anchored regular expression. In contrast to filepath.Match
+// * and ? match any character, including the / found in sensor names like "PS1/Voltage".
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var result strings.Builder
+	var runes = []rune(glob)
+
+	result.WriteString("^")
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			result.WriteString(".*")
+		case '?':
+			result.WriteString(".")
+		case '[':
+			end := i + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+			// a ] directly after the opening bracket is part of the character class
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, errors.New("Unterminated character class")
+			}
+
+			class := runes[i+1 : end]
+			result.WriteString("[")
+			if class[0] == '!' || class[0] == '^' {
+				result.WriteString("^")
+				class = class[1:]
+			}
+			for _, c := range class {
+				if c == '\\' || c == '[' || c == ']' {
+					result.WriteString("\\")
+				}
+				result.WriteRune(c)
+			}
+			result.WriteString("]")
+			i = end
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			result.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			result.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	result.WriteString("$")
+
+	return regexp.Compile(result.String())
+}
+
+func isRegisteredCheck(name string) bool {
+	for _, c := range RegisteredChecks() {
+		if c.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports if the rule applies to sensor of check
+func (r *ThresholdRule) Match(check string, sensor string) bool {
+	if r.Check != check {
+		return false
+	}
+
+	return r.regex.MatchString(sensor)
+}
+
+// Thresholds returns the warning and critical range of the first rule matching sensor of check.
+// If no rule matches (or rules is nil) warn and crit will be returned.
+func (rules *ThresholdRules) Thresholds(check string, sensor string, warn *Range, crit *Range) (*Range, *Range) {
+	if rules == nil {
+		return warn, crit
+	}
+
+	for i := range rules.Rules {
+		if rules.Rules[i].Match(check, sensor) {
+			return rules.Rules[i].Warning, rules.Rules[i].Critical
+		}
+	}
+
+	return warn, crit
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeThresholdRules(t *testing.T, content string) string {
+	f := filepath.Join(t.TempDir(), "rules")
+	err := ioutil.WriteFile(f, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestReadThresholdRules(t *testing.T) {
+	f := writeThresholdRules(t, `
+# comment
+voltages "PS1/*"          200:250 190:260
+voltages /^PS[0-9]+\/12V$/ 11:13   10:14
+thermal  "CPU? Temp"      ~:60    ~:70
+thermal  "CPU*"           -       ~:90
+fans     "Fan [!0]*"      1000:   -
+`)
+
+	rules, err := ReadThresholdRules(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	cmd_warn, _ := ParseRange("1")
+	cmd_crit, _ := ParseRange("2")
+
+	for _, tc := range []struct {
+		check  string
+		sensor string
+		warn   string
+		crit   string
+	}{
+		// * matches the / of the sensor name
+		{check: "voltages", sensor: "PS1/Input Voltage", warn: "200:250", crit: "190:260"},
+		{check: "voltages", sensor: "PS2/12V", warn: "11:13", crit: "10:14"},
+		{check: "voltages", sensor: "PS2/12V Standby", warn: "1", crit: "2"},
+		// globs are anchored
+		{check: "voltages", sensor: "Backup PS1/12V", warn: "1", crit: "2"},
+		// first match wins
+		{check: "thermal", sensor: "CPU1 Temp", warn: "~:60", crit: "~:70"},
+		// - doesn't set the range
+		{check: "thermal", sensor: "CPU10 Temp", warn: "", crit: "~:90"},
+		{check: "thermal", sensor: "Inlet Temp", warn: "1", crit: "2"},
+		{check: "fans", sensor: "Fan 1A", warn: "1000:", crit: ""},
+		{check: "fans", sensor: "Fan 0A", warn: "1", crit: "2"},
+		// rules only apply to their check
+		{check: "fans", sensor: "CPU1 Temp", warn: "1", crit: "2"},
+	} {
+		warn, crit := rules.Thresholds(tc.check, tc.sensor, cmd_warn, cmd_crit)
+		if warn.String() != tc.warn || crit.String() != tc.crit {
+			t.Errorf("%s %q: expected %q/%q but got %q/%q", tc.check, tc.sensor, tc.warn, tc.crit, warn.String(), crit.String())
+		}
+	}
+
+	var no_rules *ThresholdRules
+	warn, crit := no_rules.Thresholds("thermal", "CPU1 Temp", cmd_warn, cmd_crit)
+	if warn != cmd_warn || crit != cmd_crit {
+		t.Errorf("Expected the command line thresholds without rules")
+	}
+}
+
+func TestReadThresholdRulesInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		err  string
+	}{
+		{line: `thermal "CPU*" ~:60`, err: "Expected 4 fields but found 3"},
+		{line: `cooling "CPU*" ~:60 ~:70`, err: "Unknown check cooling"},
+		{line: `thermal /CPU[/ ~:60 ~:70`, err: "Invalid regular expression /CPU[/"},
+		{line: `thermal "CPU[1" ~:60 ~:70`, err: "Invalid pattern CPU[1"},
+		{line: `thermal "CPU* ~:60 ~:70`, err: "Unterminated double quote"},
+		{line: `thermal "CPU*" 60:10 ~:70`, err: "Start of range 60:10 is greater than the end"},
+	} {
+		f := writeThresholdRules(t, "# comment\n\n"+tc.line+"\n")
+
+		_, err := ReadThresholdRules(f)
+		if err == nil {
+			t.Errorf("Line %q: expected an error", tc.line)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), f+", line 3: "+tc.err) {
+			t.Errorf("Line %q: unexpected error %q", tc.line, err.Error())
+		}
+	}
+}
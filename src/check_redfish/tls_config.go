@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// TlsOptions - TLS settings for the connection to the management board
+type TlsOptions struct {
+	InsecureSSL bool
+	// PEM file with the CA certificates to trust instead of the system trust store
+	CaFile string
+	// PEM files of client certificate and key for mutual TLS
+	ClientCert string
+	ClientKey  string
+	// SHA256 fingerprint of the certificate of the management board, e.g. for self-signed certificates
+	Fingerprint string
+}
+
+// NormaliseFingerprint converts a SHA256 fingerprint in hexadecimal notation with or without colons
+// to lower case hexadecimal notation without colons
+func NormaliseFingerprint(fp string) (string, error) {
+	result := strings.ToLower(strings.Replace(strings.TrimSpace(fp), ":", "", -1))
+
+	raw, err := hex.DecodeString(result)
+	if err != nil || len(raw) != sha256.Size {
+		return "", errors.New(fmt.Sprintf("Invalid SHA256 fingerprint %s", fp))
+	}
+
+	return result, nil
+}
+
+// NewTlsConfig creates the TLS configuration for opts
+func NewTlsConfig(opts TlsOptions) (*tls.Config, error) {
+	var result = tls.Config{
+		InsecureSkipVerify: opts.InsecureSSL,
+	}
+
+	if opts.CaFile != "" {
+		pem, err := ioutil.ReadFile(opts.CaFile)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Can't read CA file: %s", err.Error()))
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New(fmt.Sprintf("No PEM encoded certificates found in CA file %s", opts.CaFile))
+		}
+		result.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, errors.New("Client certificate and client key must be used together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Can't load client certificate: %s", err.Error()))
+		}
+		result.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.Fingerprint != "" {
+		fingerprint, err := NormaliseFingerprint(opts.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		// the pinned certificate replaces the verification of the certificate chain and the host name
+		result.InsecureSkipVerify = true
+		result.VerifyPeerCertificate = func(raw_certs [][]byte, _ [][]*x509.Certificate) error {
+			if len(raw_certs) == 0 {
+				return errors.New("Management board didn't send a certificate")
+			}
+
+			sum := sha256.Sum256(raw_certs[0])
+			found := hex.EncodeToString(sum[:])
+			if found != fingerprint {
+				return errors.New(fmt.Sprintf("SHA256 fingerprint %s of the certificate doesn't match the pinned fingerprint", found))
+			}
+			return nil
+		}
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormaliseFingerprint(t *testing.T) {
+	const expected = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	for _, fp := range []string{
+		expected,
+		strings.ToUpper(expected),
+		"01:23:45:67:89:AB:CD:EF:01:23:45:67:89:ab:cd:ef:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:ab:cd:ef",
+		" " + expected + "\n",
+	} {
+		result, err := NormaliseFingerprint(fp)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", fp, err.Error())
+		} else if result != expected {
+			t.Errorf("%q: expected %s but got %s", fp, expected, result)
+		}
+	}
+
+	for _, fp := range []string{"", "01:23", expected + "01", strings.Replace(expected, "0", "g", 1)} {
+		_, err := NormaliseFingerprint(fp)
+		if err == nil {
+			t.Errorf("%q: expected an error", fp)
+		}
+	}
+}
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bmc.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}
+}
+
+func tlsGet(t *testing.T, url string, opts TlsOptions) error {
+	cfg, err := NewTlsConfig(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	client := http.Client{
+		Transport: &http.Transport{TLSClientConfig: cfg},
+	}
+	defer client.CloseIdleConnections()
+
+	response, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func TestNewTlsConfig(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// rejected certificates are expected, don't log the failed handshakes
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	// httptest uses the same certificate for all servers
+	other := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	other.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	other.TLS = &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}}
+	other.StartTLS()
+	defer other.Close()
+
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// fingerprint in the colon separated upper case format of openssl x509 -fingerprint
+	var colons = make([]string, 0)
+	for i := 0; i < len(fingerprint); i += 2 {
+		colons = append(colons, fingerprint[i:i+2])
+	}
+
+	ca_file := filepath.Join(t.TempDir(), "ca.pem")
+	err := ioutil.WriteFile(ca_file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		url  string
+		opts TlsOptions
+		err  string
+	}{
+		{name: "untrusted certificate", url: srv.URL, err: "certificate signed by unknown authority"},
+		{name: "insecure", url: srv.URL, opts: TlsOptions{InsecureSSL: true}},
+		{name: "CA file", url: srv.URL, opts: TlsOptions{CaFile: ca_file}},
+		{name: "pinned certificate", url: srv.URL, opts: TlsOptions{Fingerprint: strings.Join(colons, ":")}},
+		{name: "pinned certificate, lower case", url: srv.URL, opts: TlsOptions{Fingerprint: strings.ToLower(fingerprint)}},
+		{
+			name: "different certificate",
+			url:  srv.URL,
+			opts: TlsOptions{Fingerprint: strings.Repeat("00", sha256.Size)},
+			err:  "SHA256 fingerprint " + strings.ToLower(fingerprint) + " of the certificate doesn't match the pinned fingerprint",
+		},
+		{
+			// insecure-ssl doesn't disable the pinning
+			name: "different server, insecure",
+			url:  other.URL,
+			opts: TlsOptions{Fingerprint: fingerprint, InsecureSSL: true},
+			err:  "doesn't match the pinned fingerprint",
+		},
+	} {
+		err := tlsGet(t, tc.url, tc.opts)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", tc.name, err.Error())
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error %q but got %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestNewTlsConfigInvalid(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	err := ioutil.WriteFile(empty, []byte("no certificate\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts TlsOptions
+		err  string
+	}{
+		{name: "missing CA file", opts: TlsOptions{CaFile: empty + ".missing"}, err: "Can't read CA file"},
+		{name: "CA file without certificates", opts: TlsOptions{CaFile: empty}, err: "No PEM encoded certificates found in CA file"},
+		{name: "client certificate without key", opts: TlsOptions{ClientCert: empty}, err: "Client certificate and client key must be used together"},
+		{name: "invalid client certificate", opts: TlsOptions{ClientCert: empty, ClientKey: empty}, err: "Can't load client certificate"},
+		{name: "invalid fingerprint", opts: TlsOptions{Fingerprint: "00:11"}, err: "Invalid SHA256 fingerprint 00:11"},
+	} {
+		_, err := NewTlsConfig(tc.opts)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error %q but got %v", tc.name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Verbosity levels as defined in https://nagios-plugins.org/doc/guidelines.html#AEN41
+const (
+	VERBOSE_REQUESTS = 1 // requested URLs, status codes, timings and retries
+	VERBOSE_HEADERS  = 2 // request and response headers
+	VERBOSE_BODIES   = 3 // raw bodies of requests and responses
+)
+
+const REDACTED = "<redacted>"
+
+// string values of JSON keys ending in one of the names of credentials, e.g. "Password", "password" or "AuthToken"
+var redact_secrets_regexp = regexp.MustCompile(`(?i)("[^"]*(?:password|passphrase|secret|token|privatekey|authenticationkey|encryptionkey)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// headers with credentials
+var redact_headers = []string{"Authorization", "X-Auth-Token", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// VerbosityFlag - boolean command line option (-v, -vv or -vvv) raising the verbosity to Level
+type VerbosityFlag struct {
+	Verbosity *int
+	Level     int
+}
+
+func (f *VerbosityFlag) String() string {
+	if f.Verbosity == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*f.Verbosity >= f.Level)
+}
+
+func (f *VerbosityFlag) Set(s string) error {
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	if enabled && *f.Verbosity < f.Level {
+		*f.Verbosity = f.Level
+	}
+	return nil
+}
+
+func (f *VerbosityFlag) IsBoolFlag() bool {
+	return true
+}
+
+// Trace writes the message to standard error if verbosity is at least level
+func Trace(verbosity int, level int, format string, a ...interface{}) {
+	if verbosity < level {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "DEBUG: "+format+"\n", a...)
+}
+
+// RedactHeaders returns the headers, one per line, with the values of headers containing credentials replaced
+func RedactHeaders(header http.Header) string {
+	var result = make([]string, 0)
+
+	for key, values := range header {
+		for _, v := range values {
+			for _, r := range redact_headers {
+				if strings.EqualFold(strings.TrimSpace(key), r) {
+					v = REDACTED
+				}
+			}
+			result = append(result, key+": "+v)
+		}
+	}
+
+	sort.Strings(result)
+	return strings.Join(result, "\n")
+}
+
+// RedactBody replaces the values of fields containing credentials (e.g. Password) in a JSON body
+func RedactBody(body []byte) string {
+	return redact_secrets_regexp.ReplaceAllString(string(body), `$1"`+REDACTED+`"`)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	for _, tc := range []struct {
+		body     string
+		expected string
+	}{
+		{
+			body:     `{"UserName": "admin", "Password": "secret"}`,
+			expected: `{"UserName": "admin", "Password": "<redacted>"}`,
+		},
+		{
+			body:     `{"UserName":"admin","password":"se\"cret"}`,
+			expected: `{"UserName":"admin","password":"<redacted>"}`,
+		},
+		{
+			body:     `{"PASSWORD" : "secret", "OldPassword": "old"}`,
+			expected: `{"PASSWORD" : "<redacted>", "OldPassword": "<redacted>"}`,
+		},
+		{
+			body:     `{"AuthToken": "abc", "Token": "def", "ClientSecret": "ghi", "Passphrase": "jkl"}`,
+			expected: `{"AuthToken": "<redacted>", "Token": "<redacted>", "ClientSecret": "<redacted>", "Passphrase": "<redacted>"}`,
+		},
+		{
+			body:     `{"SNMP": {"AuthenticationKey": "abc", "EncryptionKey": "def"}, "PrivateKey": "-----BEGIN"}`,
+			expected: `{"SNMP": {"AuthenticationKey": "<redacted>", "EncryptionKey": "<redacted>"}, "PrivateKey": "<redacted>"}`,
+		},
+		{
+			// other fields and credentials without a value are kept
+			body:     `{"PasswordChangeRequired": false, "Password": null, "Name": "Password"}`,
+			expected: `{"PasswordChangeRequired": false, "Password": null, "Name": "Password"}`,
+		},
+	} {
+		result := RedactBody([]byte(tc.body))
+		if result != tc.expected {
+			t.Errorf("%s: expected %s but got %s", tc.body, tc.expected, result)
+		}
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		header   http.Header
+		expected string
+	}{
+		{
+			header:   http.Header{"Authorization": {"Basic YWRtaW46c2VjcmV0"}, "Accept": {"application/json"}},
+			expected: "Accept: application/json\nAuthorization: <redacted>",
+		},
+		{
+			// headers not set by http.Header.Set aren't canonicalised
+			header:   http.Header{"x-auth-token": {"abc"}, "X-AUTH-TOKEN": {"def"}, "proxy-authorization": {"ghi"}},
+			expected: "X-AUTH-TOKEN: <redacted>\nproxy-authorization: <redacted>\nx-auth-token: <redacted>",
+		},
+		{
+			header:   http.Header{"Set-Cookie": {"session=abc", "lang=en"}, "Cookie": {"session=abc"}, "Location": {"/redfish/v1/SessionService/Sessions/1"}},
+			expected: "Cookie: <redacted>\nLocation: /redfish/v1/SessionService/Sessions/1\nSet-Cookie: <redacted>\nSet-Cookie: <redacted>",
+		},
+		{
+			header:   http.Header{},
+			expected: "",
+		},
+	} {
+		result := RedactHeaders(tc.header)
+		if result != tc.expected {
+			t.Errorf("%v: expected %q but got %q", tc.header, tc.expected, result)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes content to a temporary file and renames it to f, so concurrent runs never read a
+// partial file. Missing directories are created with mode 0700.
+func WriteFileAtomic(f string, content []byte, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(f), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f), "."+filepath.Base(f))
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Chmod(mode)
+	if err == nil {
+		_, err = tmp.Write(content)
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f)
+}